@@ -0,0 +1,162 @@
+package extcap
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"fmt"
+	"net/netip"
+	"time"
+)
+
+// Anonymizer performs prefix-preserving anonymization of IP addresses (Crypto-PAn) and randomization of MAC addresses,
+// so captures can be shared without leaking the internal topology. The same key gives the same mapping.
+type Anonymizer struct {
+	block cipher.Block
+	pad   [16]byte
+}
+
+// NewAnonymizer creates Anonymizer with 32 bytes key, the first half is the AES key and the second half the pad
+func NewAnonymizer(key []byte) (*Anonymizer, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("%w: expected 32 bytes, got %d", ErrInvalidKey, len(key))
+	}
+	block, err := aes.NewCipher(key[:16])
+	if err != nil {
+		return nil, err
+	}
+	a := &Anonymizer{block: block}
+	block.Encrypt(a.pad[:], key[16:])
+	return a, nil
+}
+
+// AnonymizeAddr returns anonymized address, addresses sharing a prefix share the prefix of the same length after
+// anonymization
+func (a *Anonymizer) AnonymizeAddr(addr netip.Addr) netip.Addr {
+	if addr.Is4() {
+		ip := addr.As4()
+		a.anonymize(ip[:])
+		return netip.AddrFrom4(ip)
+	}
+	ip := addr.As16()
+	a.anonymize(ip[:])
+	return netip.AddrFrom16(ip)
+}
+
+// anonymize anonymizes the 4 or 16 bytes address in place
+func (a *Anonymizer) anonymize(ip []byte) {
+	var in, out [16]byte
+	otp := make([]byte, len(ip))
+	for pos := 0; pos < len(ip)*8; pos++ {
+		// the first pos bits are taken from the address, the rest from the pad
+		in = a.pad
+		copy(in[:pos/8], ip)
+		if bits := pos % 8; bits != 0 {
+			mask := byte(0xff) << (8 - bits)
+			in[pos/8] = ip[pos/8]&mask | a.pad[pos/8]&^mask
+		}
+		a.block.Encrypt(out[:], in[:])
+		otp[pos/8] |= out[0] >> 7 << (7 - pos%8)
+	}
+	for i := range ip {
+		ip[i] ^= otp[i]
+	}
+}
+
+// AnonymizeMAC returns randomized locally administered unicast MAC address for the 6 bytes MAC address.
+// Group addresses, e.g. broadcast, are returned unchanged.
+func (a *Anonymizer) AnonymizeMAC(mac [6]byte) [6]byte {
+	if mac[0]&1 != 0 {
+		return mac
+	}
+	var in, out [16]byte
+	in = a.pad
+	for i, b := range mac {
+		in[i] ^= b
+	}
+	a.block.Encrypt(out[:], in[:])
+	copy(mac[:], out[:6])
+	mac[0] = mac[0]&^1 | 2
+	return mac
+}
+
+// Transform returns transform anonymizing the addresses in Ethernet, ARP, IPv4 and IPv6 headers of packets with
+// the link type. The IPv4, TCP, UDP and ICMPv6 checksums are updated. Addresses in payloads, e.g. in ICMP errors
+// or DNS, are not changed.
+func (a *Anonymizer) Transform(dlt DLT) (Transform, error) {
+	link, err := packetLinkOf(dlt)
+	if err != nil {
+		return nil, err
+	}
+	return func(ts time.Time, data []byte) (time.Time, []byte, bool) {
+		a.anonymizePacket(link, data)
+		return ts, data, true
+	}, nil
+}
+
+func (a *Anonymizer) anonymizePacket(link filterLink, data []byte) {
+	l := parseLayers(link, data)
+	if l.ethernet >= 0 {
+		a.anonymizeMACAt(data, l.ethernet)
+		a.anonymizeMACAt(data, l.ethernet+6)
+	}
+	if l.network < 0 {
+		return
+	}
+
+	n := l.network
+	var old [32]byte
+	var addrs []byte
+	switch l.etherType {
+	case etherTypeARP:
+		// Ethernet and IPv4 only
+		if binary.BigEndian.Uint16(data[n:]) != 1 || binary.BigEndian.Uint16(data[n+2:]) != etherTypeIPv4 ||
+			data[n+4] != 6 || data[n+5] != 4 || len(data) < n+28 {
+			return
+		}
+		a.anonymizeMACAt(data, n+8)
+		a.anonymize(data[n+14 : n+18])
+		a.anonymizeMACAt(data, n+18)
+		a.anonymize(data[n+24 : n+28])
+		return
+	case etherTypeIPv4:
+		addrs = data[n+12 : n+20]
+		copy(old[:], addrs)
+		a.anonymize(addrs[:4])
+		a.anonymize(addrs[4:])
+		if ihl := int(data[n]&0x0f) * 4; ihl >= 20 && len(data) >= n+ihl {
+			binary.BigEndian.PutUint16(data[n+10:], 0)
+			binary.BigEndian.PutUint16(data[n+10:], checksum(data[n:n+ihl]))
+		}
+	case etherTypeIPv6:
+		addrs = data[n+8 : n+40]
+		copy(old[:], addrs)
+		a.anonymize(addrs[:16])
+		a.anonymize(addrs[16:])
+	}
+
+	// the addresses are part of the pseudo header of the transport checksum
+	if l.transport < 0 || transportHeaderLen(l.protocol, data, l.transport) == 0 {
+		return
+	}
+	t := l.transport
+	switch l.protocol {
+	case ipProtoTCP:
+		updateChecksum(data, t+16, old[:len(addrs)], addrs)
+	case ipProtoUDP:
+		// zero checksum over IPv4 means no checksum
+		if binary.BigEndian.Uint16(data[t+6:]) != 0 {
+			updateChecksum(data, t+6, old[:len(addrs)], addrs)
+			if binary.BigEndian.Uint16(data[t+6:]) == 0 {
+				binary.BigEndian.PutUint16(data[t+6:], 0xffff)
+			}
+		}
+	case ipProtoICMPv6:
+		updateChecksum(data, t+2, old[:len(addrs)], addrs)
+	}
+}
+
+func (a *Anonymizer) anonymizeMACAt(data []byte, off int) {
+	mac := a.AnonymizeMAC([6]byte(data[off : off+6]))
+	copy(data[off:], mac[:])
+}
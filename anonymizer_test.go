@@ -0,0 +1,77 @@
+package extcap
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// key of the Crypto-PAn reference implementation
+var cryptoPAnKey = []byte{
+	21, 34, 23, 141, 51, 164, 207, 128, 19, 10, 91, 22, 73, 144, 125, 16,
+	216, 152, 143, 131, 121, 121, 101, 39, 98, 87, 76, 45, 42, 132, 34, 2,
+}
+
+func TestAnonymizeAddr(t *testing.T) {
+	a, err := NewAnonymizer(cryptoPAnKey)
+	require.NoError(t, err)
+
+	for from, to := range map[string]string{
+		"128.11.68.132":   "135.242.180.132",
+		"129.118.74.4":    "134.136.186.123",
+		"130.132.252.244": "133.68.164.234",
+		"141.223.7.43":    "141.167.8.160",
+	} {
+		assert.Equal(t, to, a.AnonymizeAddr(netip.MustParseAddr(from)).String(), from)
+	}
+
+	x := a.AnonymizeAddr(netip.MustParseAddr("2001:db8:1:2::1")).As16()
+	y := a.AnonymizeAddr(netip.MustParseAddr("2001:db8:1:3::1")).As16()
+	assert.Equal(t, x[:7], y[:7])
+	assert.NotEqual(t, x[7], y[7])
+
+	_, err = NewAnonymizer(cryptoPAnKey[:16])
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func TestAnonymizerTransform(t *testing.T) {
+	a, err := NewAnonymizer(cryptoPAnKey)
+	require.NoError(t, err)
+	transform, err := a.Transform(DLT{Number: 1})
+	require.NoError(t, err)
+
+	frame := append([]byte(nil), filterTCP4...)
+	copy(frame[24:], checksumBytes(frame[14:34]))
+	copy(frame[50:], checksumBytes(tcp4PseudoPacket(frame)))
+
+	_, out, keep := transform(time.Now(), frame)
+	require.True(t, keep)
+	assert.Equal(t, byte(2), out[0]&3)
+	assert.NotEqual(t, filterTCP4[:12], out[:12])
+	assert.NotEqual(t, filterTCP4[26:34], out[26:34])
+	assert.Zero(t, checksum(out[14:34]))
+	assert.Zero(t, checksum(tcp4PseudoPacket(out)))
+
+	arp := append([]byte(nil), filterARP...)
+	_, out, _ = transform(time.Now(), arp)
+	assert.Equal(t, filterARP[:6], out[:6], "broadcast is kept")
+
+	_, err = a.Transform(DLT{Number: 147})
+	assert.ErrorIs(t, err, ErrUnsupportedLinkType)
+}
+
+func checksumBytes(data []byte) []byte {
+	c := checksum(data)
+	return []byte{byte(c >> 8), byte(c)}
+}
+
+// tcp4PseudoPacket returns the TCP segment of the Ethernet frame prefixed with the IPv4 pseudo header
+func tcp4PseudoPacket(frame []byte) []byte {
+	segment := frame[34:]
+	b := append([]byte(nil), frame[26:34]...)
+	b = append(b, 0, ipProtoTCP, 0, byte(len(segment)))
+	return append(b, segment...)
+}
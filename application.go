@@ -1,10 +1,16 @@
 package extcap
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/urfave/cli/v2"
 )
@@ -43,23 +49,99 @@ type App struct {
 	GetDLT func(iface string) (DLT, error)
 
 	// GetConfigOptions returns configuration parameters for given interface. Optional.
-	GetConfigOptions func(iface string) ([]ConfigOption, error)
+	// Current are the values of configuration options already entered in Wireshark GUI,
+	// so options depending on other options (e.g. channel list depends on selected device) can be computed.
+	GetConfigOptions func(iface string, current Options) ([]ConfigOption, error)
 
 	// GetAllConfigOptions returns all possible configuration options. Optional (interfaces do not have any configuration options).
 	GetAllConfigOptions func() []ConfigOption
 
+	// ReloadOption returns refreshed values of selector option with reload button for given interface. Optional.
+	// Current are the values of configuration options already entered in Wireshark GUI.
+	ReloadOption func(iface, option string, current Options) ([]OptValue, error)
+
+	// RequiredGroups are groups of options at least one of which must be set to start capture. Optional.
+	RequiredGroups []RequiredGroup
+
+	// Translate localizes human-readable strings (displays, tooltips, placeholders and groups) before they are shown in Wireshark GUI.
+	// The key is the string as defined by the application. Optional.
+	Translate func(key string) string
+
+	// Controls are the controls of the interface toolbar, listed together with the interfaces.
+	// Unless set explicitly, controls are numbered in the order they are defined. Optional.
+	Controls []ToolbarControl
+
+	// StatsReporter periodically sends capture statistics counted by CaptureSession to the interface toolbar.
+	// It runs only when Wireshark provides control pipes. Optional.
+	StatsReporter *StatsReporter
+
+	// Autostop returns the conditions stopping the capture, e.g. presets.Autostop reading the preset options.
+	// When one is reached, CaptureSession.Context is cancelled with ErrAutostop. Optional.
+	Autostop func(opts Options) Autostop
+
+	// UserSpaceFilter makes App compile the capture filter with CompileFilter, packets not passing it are not written
+	// with CaptureSession.Writer. It is for sources which can not filter natively. Optional.
+	UserSpaceFilter bool
+
+	// Transforms returns transforms applied to packets written with CaptureSession.Writer, after the user space filter.
+	// They are applied the same way to packets written directly and with Pump. Optional.
+	Transforms func(opts Options) []Transform
+
+	// ValidateFilter verifies if the capture filter is valid for the interface, Wireshark shows the error
+	// while the user types the filter. If it is not defined and UserSpaceFilter is set, CompileFilter is used. Optional.
+	ValidateFilter func(iface, filter string) error
+
 	// VerifyCaptureFilter verifies if the provided filter is valid. Optional.
+	//
+	// Deprecated: use ValidateFilter, which gets the interface too.
 	VerifyCaptureFilter func(filter string) error
 
-	// StartCapture starts capture process. Should be implemented. Opts are the configuration options for capture on given interface.
-	StartCapture func(iface string, fifo io.WriteCloser, filter string, opts map[string]interface{}) error
+	// StartCapture starts capture process. Should be implemented. Session holds the interface, fifo, filter,
+	// configuration options for capture on given interface and the toolbar control channel.
+	StartCapture func(session *CaptureSession) error
+
+	// NewPacketWriter creates PacketWriter passed to StartCapture as CaptureSession.Writer,
+	// it is called with the fifo and DLT of the interface, e.g. AutoFormat or PcapFormat. If it is not defined then Writer is nil. Optional.
+	NewPacketWriter PacketWriterFunc
+
+	// HandleSignals makes SIGINT and SIGTERM cancel CaptureSession.Context instead of terminating the process,
+	// so the Writer is flushed and closed after StartCapture returns. StartCapture must return when the Context is done,
+	// the signal after the first one terminates the process. Optional.
+	HandleSignals bool
+
+	// FifoFlushInterval enables buffering of the fifo, small writes are coalesced and flushed at least every interval.
+	// If it is not defined then every write goes to the fifo directly. Optional.
+	FifoFlushInterval time.Duration
+
+	// ProfilesFile is the JSON file with named option presets selected with --profile.
+	// If it is not defined then <user config dir>/<application-name>/profiles.json is used.
+	ProfilesFile string
 
 	// OpenPipe opens fifo pipe to write capture results. If it is not defined then default is used.
 	OpenPipe func(string) (io.WriteCloser, error)
+
+	// CreateFifo makes the default OpenPipe create the fifo when it does not exist (POSIX only), so the capture
+	// can be started from a terminal and read with wireshark -k -i <fifo>. Meant for development. Optional.
+	CreateFifo bool
+
+	// PipeOpenTimeout limits how long the default OpenPipe waits for Wireshark to open its end of the fifo.
+	// If it is not defined then DefaultPipeOpenTimeout is used. Optional.
+	PipeOpenTimeout time.Duration
+
+	// options registered as cli flags, by name
+	registeredOpts map[string]ConfigOption
 }
 
-// Run executes the main application loop
+// Run executes the main application loop, errors are printed to stderr and the process exits with -1
 func (extapp App) Run(arguments []string) {
+	if err := extapp.run(arguments); err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(-1)
+	}
+}
+
+// run executes the main application loop and returns the error Run exits with
+func (extapp App) run(arguments []string) error {
 	app := cli.NewApp()
 
 	// set version information
@@ -112,6 +194,11 @@ func (extapp App) Run(arguments []string) {
 			Usage: "list the additional configuration for an interface",
 		},
 
+		&cli.StringFlag{
+			Name:  "extcap-reload-option",
+			Usage: "reload values of the selector `<option>`",
+		},
+
 		&cli.BoolFlag{
 			Name:  "capture",
 			Usage: "run the capture",
@@ -124,52 +211,54 @@ func (extapp App) Run(arguments []string) {
 
 		&cli.StringFlag{
 			Name:  "fifo",
-			Usage: "dump data to file or `<fifo>`",
+			Usage: "dump data to file or `<fifo>`, - writes to stdout",
+		},
+
+		&cli.StringFlag{
+			Name:  "extcap-control-in",
+			Usage: "read toolbar control messages from `<fifo>`",
+		},
+
+		&cli.StringFlag{
+			Name:  "extcap-control-out",
+			Usage: "write toolbar control messages to `<fifo>`",
+		},
+
+		&cli.StringFlag{
+			Name:  "profile",
+			Usage: "load option values from the saved `<profile>`",
 		},
 
 		// { "debug", no_argument, NULL, EXTCAP_OPT_DEBUG}, \
 		// { "debug-file", required_argument, NULL, EXTCAP_OPT_DEBUG_FILE}
 	}
 
+	var opts []ConfigOption
 	if extapp.GetAllConfigOptions != nil {
-		opts := extapp.GetAllConfigOptions()
-		for _, opt := range opts {
-			switch opt.(type) {
-			case *ConfigStringOpt:
-				app.Flags = append(app.Flags, &cli.StringFlag{
-					Name:     opt.call(),
-					Usage:    opt.display(),
-					Required: opt.isRequired(),
-					Value:    opt.(*ConfigStringOpt).defaultValue,
-				})
-			case *ConfigBoolOpt:
-				app.Flags = append(app.Flags, &cli.BoolFlag{
-					Name:     opt.call(),
-					Usage:    opt.display(),
-					Required: opt.isRequired(),
-					Value:    opt.(*ConfigBoolOpt).defaultValue,
-				})
-			case *ConfigIntegerOpt:
-				app.Flags = append(app.Flags, &cli.IntFlag{
-					Name:     opt.call(),
-					Usage:    opt.display(),
-					Required: opt.isRequired(),
-					Value:    opt.(*ConfigIntegerOpt).defaultValue,
-				})
-				// case *SelectorConfig:
-			default:
-				errStr := fmt.Sprintf("Unknown config option type: %T", opt)
-				panic(errStr)
-			}
-		}
+		opts = extapp.GetAllConfigOptions()
 	}
 
-	app.Action = extapp.mainAction
+	// Options of the interface, not covered by GetAllConfigOptions, are registered as well,
+	// so everything Wireshark passes for the interface can be parsed
+	if iface, ok := interfaceArg(arguments); ok && extapp.GetConfigOptions != nil {
+		ifaceOpts, err := extapp.GetConfigOptions(iface, Options{})
+		if err != nil {
+			return err
+		}
+		opts = append(opts, ifaceOpts...)
+	}
 
-	if err := app.Run(arguments); err != nil {
-		_, _ = fmt.Fprintln(os.Stderr, err)
-		os.Exit(-1)
+	extapp.registeredOpts = make(map[string]ConfigOption)
+	for _, opt := range opts {
+		if _, ok := extapp.registeredOpts[opt.call()]; ok {
+			continue
+		}
+		extapp.registeredOpts[opt.call()] = opt
+		app.Flags = append(app.Flags, optionFlag(opt))
 	}
+
+	app.Action = extapp.mainAction
+	return app.Run(arguments)
 }
 
 func (extapp App) mainAction(ctx *cli.Context) error {
@@ -183,9 +272,18 @@ func (extapp App) mainAction(ctx *cli.Context) error {
 
 		fmt.Println(extapp.Version)
 		for i := range ifaces {
+			ifaces[i].Display = extapp.translate(ifaces[i].Display)
 			fmt.Println(ifaces[i])
 		}
 
+		if err = numberControls(extapp.Controls); err != nil {
+			return err
+		}
+		for _, control := range extapp.Controls {
+			control.setTranslator(extapp.Translate)
+			fmt.Println(control)
+		}
+
 		return nil
 	}
 
@@ -201,6 +299,7 @@ func (extapp App) mainAction(ctx *cli.Context) error {
 			return err
 		}
 
+		dlt.Display = extapp.translate(dlt.Display)
 		fmt.Println(dlt)
 		return nil
 	}
@@ -216,14 +315,33 @@ func (extapp App) mainAction(ctx *cli.Context) error {
 			return ErrNoInterfaceSpecified
 		}
 
+		current, err := extapp.optionValues(ctx)
+		if err != nil {
+			return err
+		}
+
 		iface := ctx.String("extcap-interface")
-		opts, err := extapp.GetConfigOptions(iface)
+		opts, err := extapp.GetConfigOptions(iface, current)
 		if err != nil {
 			return err
 		}
 
+		if err = numberOptions(opts); err != nil {
+			return err
+		}
+		for i := range opts {
+			if err = opts[i].resolveDefault(); err != nil {
+				return err
+			}
+			opts[i].setTranslator(extapp.Translate)
+		}
+
+		// Print refreshed values of the option which reload button was pressed
+		if ctx.IsSet("extcap-reload-option") {
+			return extapp.reloadOption(ctx.String("extcap-reload-option"), iface, opts, current)
+		}
+
 		for i := range opts {
-			opts[i].setNumber(i)
 			fmt.Println(opts[i])
 		}
 
@@ -243,17 +361,31 @@ func (extapp App) mainAction(ctx *cli.Context) error {
 		fifo := ctx.String("fifo")
 		filter := ctx.String("extcap-capture-filter")
 
-		opts := make(map[string]interface{})
-		for _, name := range ctx.FlagNames() {
-			if name == "extcap-interface" || name == "fifo" || name == "extcap-capture-filter" {
-				continue
-			}
-			opts[name] = ctx.Value(name)
+		if err := extapp.applyProfile(ctx); err != nil {
+			return err
+		}
+
+		opts, err := extapp.optionValues(ctx)
+		if err != nil {
+			return err
+		}
+		if err = extapp.validateOptions(opts); err != nil {
+			return err
+		}
+		err = errors.Join(
+			checkRequired(opts, extapp.registeredOpts),
+			checkDependencies(opts, extapp.registeredOpts),
+			checkRequiredGroups(opts, extapp.RequiredGroups),
+		)
+		if err != nil {
+			return err
 		}
 
 		openPipeFunc := extapp.OpenPipe
 		if openPipeFunc == nil {
-			openPipeFunc = openPipe
+			openPipeFunc = func(name string) (io.WriteCloser, error) {
+				return openPipe(name, extapp.PipeOpenTimeout, extapp.CreateFifo)
+			}
 		}
 
 		pipe, err := openPipeFunc(fifo)
@@ -261,35 +393,313 @@ func (extapp App) mainAction(ctx *cli.Context) error {
 			return err
 		}
 
-		if err = extapp.StartCapture(iface, pipe, filter, opts); err != nil {
+		if extapp.FifoFlushInterval > 0 {
+			pipe = NewBufferedWriter(pipe, DefaultFifoBufferSize, extapp.FifoFlushInterval)
+		}
+
+		parentCtx := ctx.Context
+		if extapp.HandleSignals {
+			var stop context.CancelFunc
+			parentCtx, stop = signal.NotifyContext(parentCtx, os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			// restore default handling, so the next signal terminates the process
+			go func() {
+				<-parentCtx.Done()
+				stop()
+			}()
+		}
+
+		captureCtx, cancel := context.WithCancelCause(parentCtx)
+		defer cancel(nil)
+
+		var autostop Autostop
+		if extapp.Autostop != nil {
+			autostop = extapp.Autostop(opts)
+		}
+		if autostop.Duration > 0 {
+			var stop context.CancelFunc
+			captureCtx, stop = context.WithTimeoutCause(captureCtx, autostop.Duration, ErrAutostop)
+			defer stop()
+		}
+
+		session := &CaptureSession{
+			Context:   captureCtx,
+			Interface: iface,
+			Fifo:      pipe,
+			Filter:    filter,
+			Options:   opts,
+			cancel:    cancel,
+			autostop:  autostop,
+			start:     time.Now(),
+		}
+
+		if extapp.NewPacketWriter != nil {
+			dlt, err := extapp.GetDLT(iface)
+			if err != nil {
+				return err
+			}
+			writer, err := extapp.NewPacketWriter(pipe, dlt,
+				SectionApplication(ctx.App.Name+" "+extapp.Version.Info),
+				FirstInterface(InterfaceInfo{Name: iface, Filter: filter}),
+				HostVersion(ctx.String("extcap-version")),
+			)
+			if err != nil {
+				return err
+			}
+			session.writer = writer
+			session.Writer = countingWriter{PacketWriter: writer, session: session}
+			if extapp.Transforms != nil {
+				if transforms := extapp.Transforms(opts); len(transforms) > 0 {
+					session.Writer = NewTransformWriter(session.Writer, transforms...)
+				}
+			}
+		}
+
+		if extapp.UserSpaceFilter && filter != "" {
+			dlt, err := extapp.GetDLT(iface)
+			if err != nil {
+				return err
+			}
+			if session.PacketFilter, err = CompileFilter(filter, dlt); err != nil {
+				return err
+			}
+			if session.Writer != nil {
+				session.Writer = NewFilteredWriter(session.Writer, session.PacketFilter)
+			}
+		}
+
+		if ctx.IsSet("extcap-control-in") && ctx.IsSet("extcap-control-out") {
+			if err = numberControls(extapp.Controls); err != nil {
+				return err
+			}
+			session.Control, err = openControl(ctx.String("extcap-control-in"), ctx.String("extcap-control-out"), extapp.Controls, cancel)
+			if err != nil {
+				return err
+			}
+			defer session.Control.Close()
+
+			if extapp.StatsReporter != nil {
+				go extapp.StatsReporter.run(session)
+			}
+		}
+
+		err = pipeError(extapp.StartCapture(session))
+		if stoppedCapture(captureCtx, err) {
+			err = nil
+		}
+		return errors.Join(err, closeSession(session, err == nil))
+	}
+
+	// Validate capture filter, Wireshark treats any output as the filter being invalid and shows its first line
+	if ctx.IsSet("extcap-capture-filter") {
+		err := extapp.validateFilter(ctx.String("extcap-interface"), ctx.String("extcap-capture-filter"))
+		if err != nil {
+			fmt.Println(filterErrorLine(err))
+		}
+		return nil
+	}
+
+	return cli.ShowAppHelp(ctx)
+}
+
+// optionFlag returns cli flag for given config option
+// Required options are checked before capture is started, not by the cli,
+// as Wireshark does not pass them when querying interfaces, DLTs or configuration.
+func optionFlag(opt ConfigOption) cli.Flag {
+	switch opt.(type) {
+	case *ConfigStringOpt:
+		return &cli.StringFlag{
+			Name:    opt.call(),
+			Usage:   opt.display(),
+			EnvVars: envVars(opt),
+			Value:   opt.(*ConfigStringOpt).defaultValue,
+		}
+	case *ConfigDurationOpt:
+		var value string
+		if opt.(*ConfigDurationOpt).defaultSet {
+			value = opt.(*ConfigDurationOpt).defaultValue.String()
+		}
+		return &cli.StringFlag{
+			Name:    opt.call(),
+			Usage:   opt.display(),
+			EnvVars: envVars(opt),
+			Value:   value,
+		}
+	case *ConfigBoolOpt:
+		return &cli.BoolFlag{
+			Name:    opt.call(),
+			Usage:   opt.display(),
+			EnvVars: envVars(opt),
+			Value:   opt.(*ConfigBoolOpt).defaultValue,
+		}
+	case *ConfigEditSelectorOpt:
+		return &cli.StringFlag{
+			Name:    opt.call(),
+			Usage:   opt.display(),
+			EnvVars: envVars(opt),
+			Value:   opt.(*ConfigEditSelectorOpt).defaultValue,
+		}
+	case *ConfigMulticheckOpt:
+		return &cli.StringFlag{
+			Name:    opt.call(),
+			Usage:   opt.display(),
+			EnvVars: envVars(opt),
+			Value:   strings.Join(defaultValues(opt.(*ConfigMulticheckOpt).optValues), ","),
+		}
+	case *ConfigPasswordOpt:
+		// no default value, so the help output never contains a password
+		return &cli.StringFlag{
+			Name:    opt.call(),
+			Usage:   opt.display(),
+			EnvVars: envVars(opt),
+		}
+	case *ConfigTimestampOpt, *ConfigIntegerOpt, *ConfigUnsignedOpt, *ConfigLongOpt, *ConfigDoubleOpt:
+		// numeric values are parsed by the option itself, so parse errors name the option and expected format
+		return &cli.StringFlag{
+			Name:    opt.call(),
+			Usage:   opt.display(),
+			EnvVars: envVars(opt),
+			Value:   defaultString(opt),
+		}
+	case *ConfigSelectorOpt:
+		var value string
+		if defaults := defaultValues(opt.(*ConfigSelectorOpt).optValues); len(defaults) > 0 {
+			value = defaults[0]
+		}
+		return &cli.StringFlag{
+			Name:    opt.call(),
+			Usage:   opt.display(),
+			EnvVars: envVars(opt),
+			Value:   value,
+		}
+	case *ConfigCustomOpt:
+		return opt.(*ConfigCustomOpt).custom.Flag()
+	default:
+		errStr := fmt.Sprintf("Unknown config option type: %T", opt)
+		panic(errStr)
+	}
+}
+
+// interfaceArg returns the value of --extcap-interface found in the arguments
+func interfaceArg(arguments []string) (string, bool) {
+	for i := 1; i < len(arguments); i++ {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arguments[i], "-"), "=")
+		if name != "extcap-interface" || !strings.HasPrefix(arguments[i], "-") {
+			continue
+		}
+		if hasValue {
+			return value, true
+		}
+		if i+1 < len(arguments) {
+			return arguments[i+1], true
+		}
+	}
+	return "", false
+}
+
+// defaultString returns default value of numeric option as passed on the command line
+func defaultString(opt ConfigOption) string {
+	switch o := opt.(type) {
+	case *ConfigTimestampOpt:
+		if o.defaultSet {
+			return strconv.FormatInt(o.defaultValue.Unix(), 10)
+		}
+	case *ConfigIntegerOpt:
+		if o.defaultSet {
+			return strconv.Itoa(o.defaultValue)
+		}
+	case *ConfigUnsignedOpt:
+		if o.defaultSet {
+			return strconv.FormatUint(uint64(o.defaultValue), 10)
+		}
+	case *ConfigLongOpt:
+		if o.defaultSet {
+			return strconv.FormatInt(o.defaultValue, 10)
+		}
+	case *ConfigDoubleOpt:
+		if o.defaultSet {
+			return formatFloat(o.defaultValue)
+		}
+	}
+	return ""
+}
+
+// translate localizes human-readable string with App.Translate, if defined
+func (extapp App) translate(str string) string {
+	if extapp.Translate == nil {
+		return str
+	}
+	return extapp.Translate(str)
+}
+
+// envVars returns environment variables the value of the option flag is taken from
+func envVars(opt ConfigOption) []string {
+	if name := opt.env(); name != "" {
+		return []string{name}
+	}
+	return nil
+}
+
+// reloadOption prints refreshed values of the option requested by --extcap-reload-option
+func (extapp App) reloadOption(name, iface string, opts []ConfigOption, current Options) error {
+	// Return immediately in the case if reloading is not supported
+	if extapp.ReloadOption == nil {
+		return nil
+	}
+
+	for i := range opts {
+		if opts[i].call() != name {
+			continue
+		}
+
+		values, err := extapp.ReloadOption(iface, name, current)
+		if err != nil {
 			return err
 		}
 
+		for _, val := range values {
+			val.Display = extapp.translate(val.Display)
+			fmt.Println(val.string(opts[i].getNumber()))
+		}
+
 		return nil
 	}
 
-	// Validate capture filter
-	if ctx.IsSet("extcap-capture-filter") {
-		if extapp.VerifyCaptureFilter != nil {
-			filter := ctx.String("extcap-capture-filter")
-			err := extapp.VerifyCaptureFilter(filter)
+	return fmt.Errorf("%w: %s", ErrUnknownOption, name)
+}
+
+// optionValues collects values of the options passed on the command line
+func (extapp App) optionValues(ctx *cli.Context) (Options, error) {
+	opts := make(Options)
+	for _, name := range ctx.FlagNames() {
+		switch name {
+		case "extcap-interface", "fifo", "extcap-capture-filter", "extcap-control-in", "extcap-control-out":
+			continue
+		}
+		opts[name] = ctx.Value(name)
+		if opt, ok := extapp.registeredOpts[name]; ok {
+			val, err := opt.convert(opts[name])
 			if err != nil {
-				fmt.Println(err)
+				return nil, err
 			}
+			opts[name] = val
 		}
-		return nil
 	}
 
-	return cli.ShowAppHelp(ctx)
+	return opts, nil
 }
 
-func openPipe(name string) (io.WriteCloser, error) {
-	pipe, err := os.OpenFile(name, os.O_WRONLY, os.ModeNamedPipe)
-	if err != nil {
-		return nil, fmt.Errorf("unable to open pipe: %w", err)
+// validateOptions checks the values of the options before capture is started
+func (extapp App) validateOptions(opts Options) error {
+	for name, val := range opts {
+		if opt, ok := extapp.registeredOpts[name]; ok {
+			if err := opt.validate(val); err != nil {
+				return err
+			}
+		}
 	}
 
-	return pipe, nil
+	return nil
 }
 
 const helpTemplate = `NAME:
@@ -315,3 +725,65 @@ OPTIONS:
 COPYRIGHT:
    {{.Copyright}}{{end}}
 `
+
+// validateFilter validates the filter with ValidateFilter, VerifyCaptureFilter or the user space filter compiler
+func (extapp *App) validateFilter(iface, filter string) error {
+	switch {
+	case extapp.ValidateFilter != nil:
+		return extapp.ValidateFilter(iface, filter)
+	case extapp.VerifyCaptureFilter != nil:
+		return extapp.VerifyCaptureFilter(filter)
+	case extapp.UserSpaceFilter:
+		dlt, err := extapp.GetDLT(iface)
+		if err != nil {
+			return err
+		}
+		_, err = CompileFilter(filter, dlt)
+		return err
+	default:
+		return nil
+	}
+}
+
+// filterErrorLine formats the error as a single line, Wireshark shows only the first line of the output
+func filterErrorLine(err error) string {
+	msg := strings.Join(strings.Fields(err.Error()), " ")
+	if msg == "" {
+		return "invalid capture filter"
+	}
+	return msg
+}
+
+// stoppedCapture reports if the capture ended because Wireshark or autostop stopped it, not because it failed.
+// Wireshark stops it by closing the fifo or the control pipe. The capture may return the stop error itself
+// or the error of the context, e.g. ctx.Err().
+func stoppedCapture(ctx context.Context, err error) bool {
+	if isStop(err) {
+		return true
+	}
+	if !isStop(context.Cause(ctx)) {
+		return false
+	}
+	return err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+func isStop(err error) bool {
+	return errors.Is(err, ErrPipeClosed) || errors.Is(err, ErrHostClosed) || errors.Is(err, ErrAutostop)
+}
+
+// closeSession closes the Writer of the session, so the tail of the stream is written, and then the Fifo.
+// Fifo already closed by StartCapture or Wireshark is not an error. Other errors are returned only if reportErr is set.
+func closeSession(session *CaptureSession, reportErr bool) error {
+	var err error
+	if session.Writer != nil {
+		err = closeWriter(session.Writer)
+	}
+	if cerr := session.Fifo.Close(); err == nil && !errors.Is(cerr, os.ErrClosed) {
+		err = cerr
+	}
+	err = pipeError(err)
+	if !reportErr || errors.Is(err, os.ErrClosed) || errors.Is(err, ErrPipeClosed) {
+		return nil
+	}
+	return err
+}
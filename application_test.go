@@ -0,0 +1,192 @@
+package extcap
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bufferPipe is the fifo of the captures run by the tests
+type bufferPipe struct {
+	bytes.Buffer
+}
+
+func (p *bufferPipe) Close() error {
+	return nil
+}
+
+// runCapture runs the capture of the app on eth0 and returns what it wrote to the fifo
+func runCapture(t *testing.T, app App, args ...string) (*bufferPipe, error) {
+	pipe := new(bufferPipe)
+	app.OpenPipe = func(string) (io.WriteCloser, error) { return pipe, nil }
+	if app.GetDLT == nil {
+		app.GetDLT = func(string) (DLT, error) { return DLT{Number: 1, Name: "EN10MB"}, nil }
+	}
+	if app.NewPacketWriter == nil {
+		app.NewPacketWriter = PcapngFormat
+	}
+	err := app.run(append([]string{"extcap", "--capture", "--extcap-interface", "eth0", "--fifo", "out"}, args...))
+	return pipe, err
+}
+
+// pcapngBlock is a block of pcapng stream written in little endian
+type pcapngBlock struct {
+	blockType uint32
+	body      []byte
+}
+
+func pcapngBlocks(t *testing.T, b []byte) []pcapngBlock {
+	var blocks []pcapngBlock
+	for len(b) >= 12 {
+		length := int(binary.LittleEndian.Uint32(b[4:]))
+		require.GreaterOrEqual(t, len(b), length)
+		blocks = append(blocks, pcapngBlock{blockType: binary.LittleEndian.Uint32(b), body: b[8 : length-4]})
+		b = b[length:]
+	}
+	require.Empty(t, b)
+	return blocks
+}
+
+// isbDrops returns isb_ifdrop of the last Interface Statistics Block of the interface
+func isbDrops(t *testing.T, b []byte, id uint32) (uint64, bool) {
+	var drops uint64
+	var found bool
+	for _, block := range pcapngBlocks(t, b) {
+		if block.blockType != pcapngInterfaceStatistics || binary.LittleEndian.Uint32(block.body) != id {
+			continue
+		}
+		for opts := block.body[12:]; len(opts) >= 4; {
+			code, length := binary.LittleEndian.Uint16(opts), int(binary.LittleEndian.Uint16(opts[2:]))
+			if code == pcapngOptIsbIfDrop {
+				drops, found = binary.LittleEndian.Uint64(opts[4:]), true
+			}
+			opts = opts[4+pad4(length):]
+		}
+	}
+	return drops, found
+}
+
+func TestValidateFilter(t *testing.T) {
+	app := &App{
+		GetDLT:          func(string) (DLT, error) { return DLT{Number: 1, Name: "EN10MB"}, nil },
+		UserSpaceFilter: true,
+	}
+	assert.NoError(t, app.validateFilter("eth0", "tcp port 80"))
+	assert.ErrorIs(t, app.validateFilter("eth0", "tcp port"), ErrFilterSyntax)
+
+	app.ValidateFilter = func(iface, filter string) error {
+		return errors.New(iface + ": bad\nfilter " + filter)
+	}
+	err := app.validateFilter("eth0", "x")
+	assert.Equal(t, "eth0: bad filter x", filterErrorLine(err))
+
+	assert.NoError(t, (&App{}).validateFilter("eth0", "anything"))
+}
+
+func TestAutostop(t *testing.T) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	session := &CaptureSession{Context: ctx, cancel: cancel, autostop: Autostop{Packets: 3}}
+	w := new(recordingPacketWriter)
+	writer := countingWriter{PacketWriter: w, session: session}
+
+	assert.NoError(t, writer.WritePacket(time.Now(), []byte{1}, 1))
+	assert.NoError(t, WritePackets(writer, []Packet{{Data: []byte{2}}, {Data: []byte{3}}, {Data: []byte{4}}}))
+	assert.ErrorIs(t, context.Cause(ctx), ErrAutostop)
+	assert.ErrorIs(t, writer.WritePacket(time.Now(), []byte{5}, 1), ErrAutostop)
+	assert.Len(t, w.packets, 3)
+}
+
+func TestSessionWriterDrops(t *testing.T) {
+	pipe, err := runCapture(t, App{StartCapture: func(session *CaptureSession) error {
+		if err := session.Writer.WritePacket(time.Now(), []byte{1}, 1); err != nil {
+			return err
+		}
+		if err := session.Writer.(dropReporter).ReportDropped(0, 5); err != nil {
+			return err
+		}
+		session.CountDropped(2)
+		assert.Equal(t, uint64(7), session.Stats().Dropped)
+		return nil
+	}})
+	require.NoError(t, err)
+
+	drops, ok := isbDrops(t, pipe.Bytes(), 0)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(7), drops)
+}
+
+func TestSessionPcapng(t *testing.T) {
+	pipe, err := runCapture(t, App{StartCapture: func(session *CaptureSession) error {
+		pw := session.Pcapng()
+		require.NotNil(t, pw)
+		id, err := pw.AddInterface(DLT{Number: 105}, "wlan0")
+		if err != nil {
+			return err
+		}
+		return pw.WriteInterfacePacket(id, time.Now(), []byte{1}, 1)
+	}})
+	require.NoError(t, err)
+
+	r, err := NewPcapngReader(bytes.NewReader(pipe.Bytes()))
+	require.NoError(t, err)
+	rec, err := r.ReadRecord()
+	require.NoError(t, err)
+	assert.Equal(t, DLT{Number: 105}, rec.LinkType)
+
+	_, err = runCapture(t, App{NewPacketWriter: PcapFormat, StartCapture: func(session *CaptureSession) error {
+		assert.Nil(t, session.Pcapng())
+		return nil
+	}})
+	require.NoError(t, err)
+}
+
+func TestAutostopDuration(t *testing.T) {
+	_, err := runCapture(t, App{
+		Autostop: func(Options) Autostop { return Autostop{Duration: 10 * time.Millisecond} },
+		StartCapture: func(session *CaptureSession) error {
+			<-session.Context.Done()
+			return session.Context.Err()
+		},
+	})
+	assert.NoError(t, err)
+
+	errFailed := errors.New("source failed")
+	_, err = runCapture(t, App{
+		Autostop: func(Options) Autostop { return Autostop{Duration: 10 * time.Millisecond} },
+		StartCapture: func(session *CaptureSession) error {
+			<-session.Context.Done()
+			return errFailed
+		},
+	})
+	assert.ErrorIs(t, err, errFailed)
+}
+
+func TestHostClosed(t *testing.T) {
+	for name, result := range map[string]func(ctx context.Context) error{
+		"err":   func(ctx context.Context) error { return ctx.Err() },
+		"cause": context.Cause,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := runCapture(t, App{StartCapture: func(session *CaptureSession) error {
+				// what the control channel does when Wireshark closes the control pipe
+				session.cancel(fmt.Errorf("%w: %w", ErrHostClosed, io.EOF))
+				return result(session.Context)
+			}})
+			assert.NoError(t, err)
+		})
+	}
+
+	_, err := runCapture(t, App{StartCapture: func(session *CaptureSession) error {
+		session.cancel(errors.New("source failed"))
+		return session.Context.Err()
+	}})
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,172 @@
+package extcap
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BackpressurePolicy defines what AsyncWriter does with a packet when its queue is full
+type BackpressurePolicy int
+
+const (
+	// BackpressureBlock makes WritePacket wait for free space in the queue
+	BackpressureBlock BackpressurePolicy = iota
+	// BackpressureDropNewest drops the packet being written
+	BackpressureDropNewest
+	// BackpressureDropOldest drops the oldest queued packet to make space for the new one
+	BackpressureDropOldest
+)
+
+// AsyncWriter queues packets and writes them to the underlying PacketWriter from a dedicated goroutine,
+// so sources which can not be paused are not slowed down by Wireshark reading slowly.
+// Dropped packets are counted and reported to the underlying writer if it is PcapngWriter,
+// for the interface of the dropped packet.
+type AsyncWriter struct {
+	w       PacketWriter
+	policy  BackpressurePolicy
+	queue   chan asyncPacket
+	dropped atomic.Uint64
+
+	mu     sync.Mutex
+	err    error
+	closed bool
+	done   chan struct{}
+}
+
+type asyncPacket struct {
+	id      int
+	ts      time.Time
+	data    []byte
+	origLen int
+}
+
+// dropReporter is implemented by writers accounting dropped packets, like PcapngWriter
+type dropReporter interface {
+	ReportDropped(id int, n uint64) error
+}
+
+// interfaceWriter is implemented by writers with multiple interfaces, like PcapngWriter
+type interfaceWriter interface {
+	WriteInterfacePacket(id int, ts time.Time, data []byte, origLen int) error
+}
+
+// NewAsyncWriter starts writing packets queued in the queue of size packets to w
+func NewAsyncWriter(w PacketWriter, size int, policy BackpressurePolicy) *AsyncWriter {
+	aw := &AsyncWriter{
+		w:      w,
+		policy: policy,
+		queue:  make(chan asyncPacket, size),
+		done:   make(chan struct{}),
+	}
+
+	go aw.writeLoop()
+
+	return aw
+}
+
+// WritePacket queues copy of the packet for interface 0, error of writing previous packets is returned if there was one
+func (aw *AsyncWriter) WritePacket(ts time.Time, data []byte, origLen int) error {
+	return aw.WriteInterfacePacket(0, ts, data, origLen)
+}
+
+// WriteInterfacePacket queues copy of the packet for the interface of the underlying writer,
+// which must have WriteInterfacePacket for interfaces other than 0
+func (aw *AsyncWriter) WriteInterfacePacket(id int, ts time.Time, data []byte, origLen int) error {
+	if _, ok := aw.w.(interfaceWriter); !ok && id != 0 {
+		return fmt.Errorf("%w: %d", ErrUnknownInterface, id)
+	}
+
+	aw.mu.Lock()
+	err, closed := aw.err, aw.closed
+	aw.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if closed {
+		return ErrWriterClosed
+	}
+
+	pkt := asyncPacket{id: id, ts: ts, data: append([]byte(nil), data...), origLen: origLen}
+
+	switch aw.policy {
+	case BackpressureDropNewest:
+		select {
+		case aw.queue <- pkt:
+		default:
+			aw.drop(pkt.id)
+		}
+	case BackpressureDropOldest:
+		for {
+			select {
+			case aw.queue <- pkt:
+				return nil
+			default:
+			}
+			select {
+			case old := <-aw.queue:
+				aw.drop(old.id)
+			default:
+			}
+		}
+	default:
+		aw.queue <- pkt
+	}
+
+	return nil
+}
+
+// Dropped returns number of packets dropped because the queue was full
+func (aw *AsyncWriter) Dropped() uint64 {
+	return aw.dropped.Load()
+}
+
+// Close writes queued packets, stops the writer goroutine and closes the underlying writer if it is io.Closer.
+// It returns the first write error, calling it again returns the same result.
+// WritePacket must not be called concurrently with Close.
+func (aw *AsyncWriter) Close() error {
+	aw.mu.Lock()
+	closing := !aw.closed
+	if closing {
+		aw.closed = true
+		close(aw.queue)
+	}
+	aw.mu.Unlock()
+
+	<-aw.done
+
+	aw.mu.Lock()
+	defer aw.mu.Unlock()
+	if closing {
+		if err := closeWriter(aw.w); err != nil && aw.err == nil {
+			aw.err = err
+		}
+	}
+	return aw.err
+}
+
+func (aw *AsyncWriter) drop(id int) {
+	aw.dropped.Add(1)
+	_ = reportDropped(aw.w, id, 1)
+}
+
+func (aw *AsyncWriter) writeLoop() {
+	defer close(aw.done)
+
+	for pkt := range aw.queue {
+		var err error
+		if iw, ok := aw.w.(interfaceWriter); ok {
+			err = iw.WriteInterfacePacket(pkt.id, pkt.ts, pkt.data, pkt.origLen)
+		} else {
+			err = aw.w.WritePacket(pkt.ts, pkt.data, pkt.origLen)
+		}
+		if err != nil {
+			aw.mu.Lock()
+			if aw.err == nil {
+				aw.err = err
+			}
+			aw.mu.Unlock()
+		}
+	}
+}
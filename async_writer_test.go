@@ -0,0 +1,101 @@
+package extcap
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// blockingWriter records packets, writing waits until release is closed
+type blockingWriter struct {
+	release chan struct{}
+	mu      sync.Mutex
+	packets [][]byte
+}
+
+func (w *blockingWriter) WritePacket(_ time.Time, data []byte, _ int) error {
+	<-w.release
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.packets = append(w.packets, data)
+	return nil
+}
+
+func TestAsyncWriterPolicies(t *testing.T) {
+	testCases := []struct {
+		name     string
+		policy   BackpressurePolicy
+		expected [][]byte
+	}{
+		{"Drop newest", BackpressureDropNewest, [][]byte{{0}, {1}, {2}}},
+		{"Drop oldest", BackpressureDropOldest, [][]byte{{0}, {3}, {4}}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := &blockingWriter{release: make(chan struct{})}
+			aw := NewAsyncWriter(w, 2, tc.policy)
+
+			assert.NoError(t, aw.WritePacket(time.Now(), []byte{0}, 1))
+			// the first packet is taken by the writer goroutine, which blocks
+			assert.Eventually(t, func() bool { return len(aw.queue) == 0 }, time.Second, time.Millisecond)
+			for i := byte(1); i < 5; i++ {
+				assert.NoError(t, aw.WritePacket(time.Now(), []byte{i}, 1))
+			}
+
+			close(w.release)
+			assert.NoError(t, aw.Close())
+			assert.Equal(t, uint64(2), aw.Dropped())
+			assert.Equal(t, tc.expected, w.packets)
+			assert.ErrorIs(t, aw.WritePacket(time.Now(), nil, 0), ErrWriterClosed)
+		})
+	}
+}
+
+// interfaceWriterStub is blockingWriter with interfaces, it records interfaces of written and dropped packets
+type interfaceWriterStub struct {
+	blockingWriter
+	ids     []int
+	dropped map[int]uint64
+}
+
+func (w *interfaceWriterStub) WriteInterfacePacket(id int, ts time.Time, data []byte, origLen int) error {
+	if err := w.WritePacket(ts, data, origLen); err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.ids = append(w.ids, id)
+	return nil
+}
+
+func (w *interfaceWriterStub) ReportDropped(id int, n uint64) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.dropped[id] += n
+	return nil
+}
+
+func TestAsyncWriterInterfaces(t *testing.T) {
+	w := &interfaceWriterStub{blockingWriter: blockingWriter{release: make(chan struct{})}, dropped: make(map[int]uint64)}
+	aw := NewAsyncWriter(w, 1, BackpressureDropOldest)
+
+	assert.NoError(t, aw.WriteInterfacePacket(1, time.Now(), []byte{0}, 1))
+	assert.Eventually(t, func() bool { return len(aw.queue) == 0 }, time.Second, time.Millisecond)
+	// the packet of interface 2 is pushed out by the one of interface 3
+	assert.NoError(t, aw.WriteInterfacePacket(2, time.Now(), []byte{1}, 1))
+	assert.NoError(t, aw.WriteInterfacePacket(3, time.Now(), []byte{2}, 1))
+
+	close(w.release)
+	assert.NoError(t, aw.Close())
+	assert.Equal(t, []int{1, 3}, w.ids)
+	assert.Equal(t, map[int]uint64{2: 1}, w.dropped)
+}
+
+func TestAsyncWriterUnknownInterface(t *testing.T) {
+	aw := NewAsyncWriter(&blockingWriter{release: make(chan struct{})}, 1, BackpressureBlock)
+	assert.ErrorIs(t, aw.WriteInterfacePacket(1, time.Now(), []byte{0}, 1), ErrUnknownInterface)
+	assert.NoError(t, aw.Close())
+}
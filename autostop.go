@@ -0,0 +1,19 @@
+package extcap
+
+import "time"
+
+// Autostop are the conditions stopping the capture like dumpcap -a, zero values are not limited.
+// Packets and bytes are counted when written with CaptureSession.Writer.
+type Autostop struct {
+	// Duration stops the capture after the time since its start
+	Duration time.Duration
+	// Packets stops the capture after the number of packets
+	Packets uint64
+	// Bytes stops the capture after the number of bytes of packet data
+	Bytes uint64
+}
+
+// reached reports if the packet or byte limit is reached by the counters
+func (a Autostop) reached(packets, bytes uint64) bool {
+	return (a.Packets > 0 && packets >= a.Packets) || (a.Bytes > 0 && bytes >= a.Bytes)
+}
@@ -0,0 +1,118 @@
+package extcap
+
+import (
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/bpf"
+)
+
+// Filter evaluates classic BPF program in user space, so captures whose sources can not filter natively
+// still honor the capture filter of Wireshark. It is safe to use from multiple goroutines.
+type Filter struct {
+	expr    string
+	program []bpf.Instruction
+	vm      *bpf.VM
+}
+
+// CompileFilter compiles capture filter for packets of the link type of dlt, empty filter matches all packets.
+// Ethernet and raw IP link types and a subset of the pcap-filter syntax are supported:
+//
+//   - protocols ip, ip6, arp, tcp, udp, icmp and icmp6
+//   - [ip|ip6] [src|dst] host <address>, [src|dst] net <IPv4 network>/<bits>
+//   - [tcp|udp] [src|dst] port <number>, ports are matched in unfragmented IPv4 and in IPv6 without extension headers
+//   - not, and, or and parentheses, and and or have the same precedence and are evaluated left to right
+func CompileFilter(expr string, dlt DLT) (*Filter, error) {
+	program, err := compileFilter(expr, dlt)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := NewBPFFilter(program)
+	if err != nil {
+		return nil, err
+	}
+	f.expr = expr
+	return f, nil
+}
+
+// NewBPFFilter creates Filter of a program compiled elsewhere, e.g. with tcpdump -d
+func NewBPFFilter(program []bpf.Instruction) (*Filter, error) {
+	vm, err := bpf.NewVM(program)
+	if err != nil {
+		return nil, err
+	}
+	return &Filter{program: program, vm: vm}, nil
+}
+
+// Match runs the program on the packet data and reports if the packet passes the filter
+func (f *Filter) Match(data []byte) bool {
+	n, err := f.vm.Run(data)
+	return err == nil && n > 0
+}
+
+// Program returns the BPF program of the filter
+func (f *Filter) Program() []bpf.Instruction {
+	return f.program
+}
+
+// String returns the filter expression
+func (f *Filter) String() string {
+	return f.expr
+}
+
+// FilteredWriter writes to the underlying writer only the packets passing the filter
+type FilteredWriter struct {
+	w        PacketWriter
+	filter   *Filter
+	filtered atomic.Uint64
+}
+
+// NewFilteredWriter creates FilteredWriter writing packets passing f to w
+func NewFilteredWriter(w PacketWriter, f *Filter) *FilteredWriter {
+	return &FilteredWriter{w: w, filter: f}
+}
+
+// WritePacket writes the packet if it passes the filter
+func (fw *FilteredWriter) WritePacket(ts time.Time, data []byte, origLen int) error {
+	if !fw.filter.Match(data) {
+		fw.filtered.Add(1)
+		return nil
+	}
+	return fw.w.WritePacket(ts, data, origLen)
+}
+
+// WritePackets writes the packets of the batch passing the filter as one batch
+func (fw *FilteredWriter) WritePackets(packets []Packet) error {
+	passed := make([]Packet, 0, len(packets))
+	for _, p := range packets {
+		if fw.filter.Match(p.Data) {
+			passed = append(passed, p)
+		}
+	}
+	fw.filtered.Add(uint64(len(packets) - len(passed)))
+	if len(passed) == 0 {
+		return nil
+	}
+	return WritePackets(fw.w, passed)
+}
+
+// Flush flushes the filtered writer
+func (fw *FilteredWriter) Flush() error {
+	return flushWriter(fw.w)
+}
+
+// Filtered returns number of packets which did not pass the filter
+func (fw *FilteredWriter) Filtered() uint64 {
+	return fw.filtered.Load()
+}
+
+// ReportDropped passes the drops to the filtered writer, packets not passing the filter are not drops
+func (fw *FilteredWriter) ReportDropped(id int, n uint64) error {
+	return reportDropped(fw.w, id, n)
+}
+
+// Close closes the filtered writer
+func (fw *FilteredWriter) Close() error {
+	return closeWriter(fw.w)
+}
@@ -0,0 +1,114 @@
+package extcap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// ethernet frames used by the filter tests
+var (
+	// IPv4 TCP 10.0.0.1:1234 -> 192.168.1.2:80
+	filterTCP4 = []byte{
+		0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 0x08, 0x00,
+		0x45, 0, 0, 40, 0, 0, 0x40, 0, 64, 6, 0, 0,
+		10, 0, 0, 1, 192, 168, 1, 2,
+		0x04, 0xd2, 0, 80, 0, 0, 0, 0, 0, 0, 0, 0, 0x50, 0x02, 0, 0, 0, 0, 0, 0,
+	}
+	// IPv6 UDP [2001:db8::1]:53 -> [2001:db8::2]:5353
+	filterUDP6 = []byte{
+		0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 0x86, 0xdd,
+		0x60, 0, 0, 0, 0, 8, 17, 64,
+		0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1,
+		0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 2,
+		0, 53, 0x14, 0xe9, 0, 8, 0, 0,
+	}
+	// ARP request
+	filterARP = []byte{
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 6, 7, 8, 9, 10, 11, 0x08, 0x06,
+		0, 1, 8, 0, 6, 4, 0, 1,
+	}
+)
+
+func TestCompileFilter(t *testing.T) {
+	tests := []struct {
+		expr               string
+		tcp4, udp6, arpReq bool
+	}{
+		{"", true, true, true},
+		{"ip", true, false, false},
+		{"ip6", false, true, false},
+		{"arp", false, false, true},
+		{"tcp", true, false, false},
+		{"udp", false, true, false},
+		{"not arp", true, true, false},
+		{"port 80", true, false, false},
+		{"tcp dst port 80", true, false, false},
+		{"tcp src port 80", false, false, false},
+		{"udp port 53", false, true, false},
+		{"port 5353 or arp", false, true, true},
+		{"host 10.0.0.1", true, false, false},
+		{"dst host 10.0.0.1", false, false, false},
+		{"src 2001:db8::1", false, true, false},
+		{"net 192.168.0.0/16 && tcp", true, false, false},
+		{"!(tcp || udp)", false, false, true},
+		{"ip6 and not port 53", false, false, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expr, func(t *testing.T) {
+			f, err := CompileFilter(test.expr, DLT{Number: 1})
+			assert.NoError(t, err)
+			assert.Equal(t, test.tcp4, f.Match(filterTCP4), "IPv4 TCP")
+			assert.Equal(t, test.udp6, f.Match(filterUDP6), "IPv6 UDP")
+			assert.Equal(t, test.arpReq, f.Match(filterARP), "ARP")
+		})
+	}
+}
+
+func TestCompileFilterRawIP(t *testing.T) {
+	f, err := CompileFilter("tcp port 80", DLT{Number: 101})
+	assert.NoError(t, err)
+	assert.True(t, f.Match(filterTCP4[14:]))
+	assert.False(t, f.Match(filterUDP6[14:]))
+}
+
+func TestCompileFilterErrors(t *testing.T) {
+	for _, expr := range []string{"tcp and", "port", "port http", "(tcp", "host example.com", "foo"} {
+		_, err := CompileFilter(expr, DLT{Number: 1})
+		assert.ErrorIs(t, err, ErrFilterSyntax, expr)
+	}
+	for _, expr := range []string{"icmp port 1", "tcp host 10.0.0.1", "net 2001:db8::/32"} {
+		_, err := CompileFilter(expr, DLT{Number: 1})
+		assert.ErrorIs(t, err, ErrFilterUnsupported, expr)
+	}
+	_, err := CompileFilter("tcp", DLT{Number: 147})
+	assert.ErrorIs(t, err, ErrFilterUnsupported)
+}
+
+func TestFilteredWriter(t *testing.T) {
+	f, err := CompileFilter("tcp", DLT{Number: 1})
+	assert.NoError(t, err)
+	w := new(recordingPacketWriter)
+	fw := NewFilteredWriter(w, f)
+
+	assert.NoError(t, fw.WritePacket(time.Now(), filterTCP4, len(filterTCP4)))
+	assert.NoError(t, fw.WritePacket(time.Now(), filterARP, len(filterARP)))
+	assert.Len(t, w.packets, 1)
+	assert.Equal(t, uint64(1), fw.Filtered())
+}
+
+func TestFilteredWriterBatch(t *testing.T) {
+	f, err := CompileFilter("tcp", DLT{Number: 1})
+	assert.NoError(t, err)
+	w := new(batchRecordingWriter)
+	fw := NewFilteredWriter(w, f)
+
+	assert.NoError(t, fw.WritePackets([]Packet{{Data: filterTCP4}, {Data: filterARP}, {Data: filterTCP4}}))
+	assert.NoError(t, fw.Flush())
+	assert.Equal(t, [][]byte{filterTCP4, filterTCP4}, w.packets)
+	assert.Equal(t, 1, w.batches)
+	assert.Equal(t, 1, w.flushes)
+	assert.Equal(t, uint64(1), fw.Filtered())
+}
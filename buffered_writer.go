@@ -0,0 +1,103 @@
+package extcap
+
+import (
+	"bufio"
+	"io"
+	"sync"
+	"time"
+)
+
+// DefaultFifoBufferSize is the buffer size of BufferedWriter created by App
+const DefaultFifoBufferSize = 64 * 1024
+
+// BufferedWriter coalesces small writes to the fifo, buffered data is flushed when the buffer is full
+// and at least every flush interval, so packets of low-rate captures still appear in Wireshark live.
+type BufferedWriter struct {
+	w    io.Writer
+	buf  *bufio.Writer
+	mu   sync.Mutex
+	stop chan struct{}
+	wg   sync.WaitGroup
+	err  error
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// NewBufferedWriter creates BufferedWriter of size bytes flushing every interval
+func NewBufferedWriter(w io.Writer, size int, interval time.Duration) *BufferedWriter {
+	bw := &BufferedWriter{
+		w:    w,
+		buf:  bufio.NewWriterSize(w, size),
+		stop: make(chan struct{}),
+	}
+
+	bw.wg.Add(1)
+	go bw.flushLoop(interval)
+
+	return bw
+}
+
+// Write writes p to the buffer, error of the previous flush is returned if there was one
+func (bw *BufferedWriter) Write(p []byte) (int, error) {
+	bw.mu.Lock()
+	defer bw.mu.Unlock()
+
+	if bw.err != nil {
+		return 0, bw.err
+	}
+	return bw.buf.Write(p)
+}
+
+// Flush writes buffered data to the underlying writer
+func (bw *BufferedWriter) Flush() error {
+	bw.mu.Lock()
+	defer bw.mu.Unlock()
+
+	return bw.flushLocked()
+}
+
+// Close stops periodic flushing, flushes buffered data and closes the underlying writer if it is io.Closer.
+// Calling it again returns the same result.
+func (bw *BufferedWriter) Close() error {
+	bw.closeOnce.Do(func() {
+		close(bw.stop)
+		bw.wg.Wait()
+
+		bw.closeErr = bw.Flush()
+		if c, ok := bw.w.(io.Closer); ok {
+			if cerr := c.Close(); bw.closeErr == nil {
+				bw.closeErr = cerr
+			}
+		}
+	})
+	return bw.closeErr
+}
+
+func (bw *BufferedWriter) flushLocked() error {
+	if bw.err != nil {
+		return bw.err
+	}
+	bw.err = bw.buf.Flush()
+	return bw.err
+}
+
+func (bw *BufferedWriter) flushLoop(interval time.Duration) {
+	defer bw.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			bw.mu.Lock()
+			if bw.buf.Buffered() > 0 {
+				_ = bw.flushLocked()
+			}
+			bw.mu.Unlock()
+		case <-bw.stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package extcap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBufferedWriter(t *testing.T) {
+	out := new(safeBuffer)
+	bw := NewBufferedWriter(out, 16, 10*time.Millisecond)
+
+	_, err := bw.Write([]byte("abc"))
+	assert.NoError(t, err)
+	assert.Empty(t, out.Bytes())
+	assert.Eventually(t, func() bool { return string(out.Bytes()) == "abc" }, time.Second, time.Millisecond)
+
+	_, err = bw.Write([]byte("0123456789abcdefXYZ"))
+	assert.NoError(t, err)
+	assert.NoError(t, bw.Close())
+	assert.Equal(t, "abc0123456789abcdefXYZ", string(out.Bytes()))
+	assert.NoError(t, bw.Close())
+}
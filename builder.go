@@ -0,0 +1,65 @@
+package extcap
+
+// Short constructors for fluent definition of config options, e.g.
+//
+//	NewStringOpt("host", "Remote host").MustSet().Tooltip("Hostname or IP address").Group("Connection").Default("10.0.0.1")
+
+// NewIntegerOpt is a short form of NewConfigIntegerOpt
+func NewIntegerOpt(call, display string) *ConfigIntegerOpt {
+	return NewConfigIntegerOpt(call, display)
+}
+
+// NewUnsignedOpt is a short form of NewConfigUnsignedOpt
+func NewUnsignedOpt(call, display string) *ConfigUnsignedOpt {
+	return NewConfigUnsignedOpt(call, display)
+}
+
+// NewLongOpt is a short form of NewConfigLongOpt
+func NewLongOpt(call, display string) *ConfigLongOpt {
+	return NewConfigLongOpt(call, display)
+}
+
+// NewDoubleOpt is a short form of NewConfigDoubleOpt
+func NewDoubleOpt(call, display string) *ConfigDoubleOpt {
+	return NewConfigDoubleOpt(call, display)
+}
+
+// NewStringOpt is a short form of NewConfigStringOpt
+func NewStringOpt(call, display string) *ConfigStringOpt {
+	return NewConfigStringOpt(call, display)
+}
+
+// NewPasswordOpt is a short form of NewConfigPasswordOpt
+func NewPasswordOpt(call, display string) *ConfigPasswordOpt {
+	return NewConfigPasswordOpt(call, display)
+}
+
+// NewBoolOpt is a short form of NewConfigBoolOpt
+func NewBoolOpt(call, display string) *ConfigBoolOpt {
+	return NewConfigBoolOpt(call, display)
+}
+
+// NewTimestampOpt is a short form of NewConfigTimestampOpt
+func NewTimestampOpt(call, display string) *ConfigTimestampOpt {
+	return NewConfigTimestampOpt(call, display)
+}
+
+// NewDurationOpt is a short form of NewConfigDurationOpt
+func NewDurationOpt(call, display string) *ConfigDurationOpt {
+	return NewConfigDurationOpt(call, display)
+}
+
+// NewSelectorOpt is a short form of NewConfigSelectorOpt
+func NewSelectorOpt(call, display string) *ConfigSelectorOpt {
+	return NewConfigSelectorOpt(call, display)
+}
+
+// NewEditSelectorOpt is a short form of NewConfigEditSelectorOpt
+func NewEditSelectorOpt(call, display string) *ConfigEditSelectorOpt {
+	return NewConfigEditSelectorOpt(call, display)
+}
+
+// NewMulticheckOpt is a short form of NewConfigMulticheckOpt
+func NewMulticheckOpt(call, display string) *ConfigMulticheckOpt {
+	return NewConfigMulticheckOpt(call, display)
+}
@@ -0,0 +1,102 @@
+package extcap
+
+import (
+	"sync"
+	"time"
+)
+
+// ClockCorrector corrects timestamps of a source whose clock differs from the local one
+type ClockCorrector interface {
+	// Correct converts source timestamp to local time
+	Correct(ts time.Time) time.Time
+}
+
+// ClockCorrection makes the writer correct source-provided timestamps with c before writing,
+// so packets of remote and local sources line up on the Wireshark timeline
+func ClockCorrection(c ClockCorrector) WriterOption {
+	return func(cfg *writerConfig) {
+		cfg.corrector = c
+	}
+}
+
+// ClockOffset is a fixed offset of the source clock, positive when the source clock is ahead of the local one
+type ClockOffset time.Duration
+
+// Correct subtracts the offset from ts
+func (o ClockOffset) Correct(ts time.Time) time.Time {
+	return ts.Add(-time.Duration(o))
+}
+
+// skewMaxProbes is the number of the latest probes SkewEstimator uses
+const skewMaxProbes = 32
+
+// SkewEstimator estimates offset and drift of the source clock from periodic probes, e.g. request-response
+// exchanges with a remote agent reporting its time. Until the first probe timestamps are not corrected.
+type SkewEstimator struct {
+	mu     sync.Mutex
+	probes []skewProbe
+	// offset = intercept + slope * (ts - ref), estimated by least squares
+	ref       time.Time
+	intercept float64
+	slope     float64
+}
+
+// skewProbe is the offset of the source clock at the source time
+type skewProbe struct {
+	remote time.Time
+	offset time.Duration
+}
+
+// NewSkewEstimator creates SkewEstimator without probes
+func NewSkewEstimator() *SkewEstimator {
+	return &SkewEstimator{}
+}
+
+// AddProbe adds probe which was sent at local time sent, answered with the source time remote and received
+// at local time received. The source time is assumed to be taken in the middle of the round trip.
+func (e *SkewEstimator) AddProbe(sent, remote, received time.Time) {
+	local := sent.Add(received.Sub(sent) / 2)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.probes = append(e.probes, skewProbe{remote: remote, offset: remote.Sub(local)})
+	if len(e.probes) > skewMaxProbes {
+		e.probes = e.probes[len(e.probes)-skewMaxProbes:]
+	}
+	e.estimate()
+}
+
+// Offset returns the estimated offset of the source clock at the source time ts
+func (e *SkewEstimator) Offset(ts time.Time) time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return time.Duration(e.intercept + e.slope*float64(ts.Sub(e.ref)))
+}
+
+// Correct subtracts the estimated offset from ts
+func (e *SkewEstimator) Correct(ts time.Time) time.Time {
+	return ts.Add(-e.Offset(ts))
+}
+
+// estimate fits a line to the offsets of the probes, the caller must hold the lock
+func (e *SkewEstimator) estimate() {
+	e.ref = e.probes[0].remote
+	n := float64(len(e.probes))
+
+	var sumX, sumY, sumXX, sumXY float64
+	for _, p := range e.probes {
+		x, y := float64(p.remote.Sub(e.ref)), float64(p.offset)
+		sumX += x
+		sumY += y
+		sumXX += x * x
+		sumXY += x * y
+	}
+
+	e.slope = 0
+	if d := n*sumXX - sumX*sumX; d != 0 {
+		e.slope = (n*sumXY - sumX*sumY) / d
+	}
+	e.intercept = (sumY - e.slope*sumX) / n
+}
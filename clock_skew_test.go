@@ -0,0 +1,29 @@
+package extcap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClockOffset(t *testing.T) {
+	ts := time.Unix(1700000000, 0)
+	assert.Equal(t, ts.Add(-time.Second), ClockOffset(time.Second).Correct(ts))
+}
+
+func TestSkewEstimator(t *testing.T) {
+	e := NewSkewEstimator()
+	local := time.Unix(1700000000, 0)
+	assert.Equal(t, local, e.Correct(local))
+
+	// the source clock is 2s ahead and gains 1ms every second
+	for i := 0; i < 10; i++ {
+		sent := local.Add(time.Duration(i) * time.Second)
+		remote := sent.Add(5*time.Millisecond + 2*time.Second + time.Duration(i)*time.Millisecond)
+		e.AddProbe(sent, remote, sent.Add(10*time.Millisecond))
+	}
+
+	remote := local.Add(20*time.Second + 2*time.Second + 20*time.Millisecond)
+	assert.WithinDuration(t, local.Add(20*time.Second), e.Correct(remote), 100*time.Microsecond)
+}
@@ -1,9 +1,14 @@
 package extcap
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"os"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // ConfigOption represents config options which will be shown in Wireshark GUI
@@ -21,16 +26,33 @@ type ConfigOption interface {
 	tooltip() string
 	isRequired() bool
 	setNumber(int)
+	getNumber() int
+	convert(interface{}) (interface{}, error)
+	validate(interface{}) error
+	dependencies() (requires, conflicts []string)
+	resolveDefault() error
+	env() string
+	setTranslator(func(string) string)
 }
 
 // common for all options
 type cfg struct {
 	number     int
+	numberSet  bool
 	callValue  string
 	displayVal string
 	tooltipVal string
 	group      string
 	required   bool
+	dontSave   bool
+	requires   []string
+	conflicts  []string
+
+	lazyDefault func() error
+	validators  []func(interface{}) error
+	envVar      string
+	envDefault  func(string) error
+	translate   func(string) string
 }
 
 func (c *cfg) call() string {
@@ -45,13 +67,30 @@ func (c *cfg) tooltip() string {
 func (c *cfg) isRequired() bool {
 	return c.required
 }
+func (c *cfg) dependencies() (requires, conflicts []string) {
+	return c.requires, c.conflicts
+}
+func (c *cfg) env() string {
+	return c.envVar
+}
+func (c *cfg) setTranslator(translate func(string) string) {
+	c.translate = translate
+}
+
+// text returns translated human-readable string safe to be written into a sentence
+func (c *cfg) text(str string) string {
+	if c.translate != nil {
+		str = c.translate(str)
+	}
+	return escapeText(str)
+}
 
 func (c *cfg) string(optType string, params [][2]string) string {
 	w := new(strings.Builder)
-	_, _ = fmt.Fprintf(w, "arg {number=%d}{call=--%s}{display=%s}{type=%s}", c.number, c.callValue, c.displayVal, optType)
+	_, _ = fmt.Fprintf(w, "arg {number=%d}{call=--%s}{display=%s}{type=%s}", c.number, escapeValue(c.callValue), c.text(c.displayVal), optType)
 
 	if c.tooltipVal != "" {
-		_, _ = fmt.Fprintf(w, "{tooltip=%s}", c.tooltipVal)
+		_, _ = fmt.Fprintf(w, "{tooltip=%s}", c.text(c.tooltipVal))
 	}
 
 	if c.required {
@@ -59,34 +98,295 @@ func (c *cfg) string(optType string, params [][2]string) string {
 	}
 
 	if c.group != "" {
-		_, _ = fmt.Fprintf(w, "{group=%s", c.group)
+		_, _ = fmt.Fprintf(w, "{group=%s}", c.text(c.group))
+	}
+
+	if c.dontSave {
+		_, _ = fmt.Fprintf(w, "{save=false}")
 	}
 
 	for i := range params {
-		_, _ = fmt.Fprintf(w, "{%s=%s}", params[i][0], params[i][1])
+		val := params[i][1]
+		switch params[i][0] {
+		case "validation":
+			// regular expression is written as is, Wireshark accepts braces of quantifiers
+		case "placeholder":
+			val = c.text(val)
+		default:
+			val = escapeValue(val)
+		}
+		_, _ = fmt.Fprintf(w, "{%s=%s}", params[i][0], val)
 	}
 
 	return w.String()
 }
 
+// values formats value sentences of selector-like option, every sentence starts with new line
+func (c *cfg) values(values []OptValue) string {
+	w := new(strings.Builder)
+	for i := range values {
+		val := values[i]
+		if c.translate != nil {
+			val.Display = c.translate(val.Display)
+		}
+		_, _ = fmt.Fprintf(w, "\n%s", val.string(c.number))
+	}
+	return w.String()
+}
+
+// setNumber sets number of the option unless it was set explicitly with Number
 func (c *cfg) setNumber(i int) {
-	c.number = i
+	if !c.numberSet {
+		c.number = i
+	}
+}
+func (c *cfg) getNumber() int {
+	return c.number
+}
+
+// numberOptions assigns numbers to the options, options without explicit number are numbered by their position.
+// Error is returned if several options end up with the same number.
+func numberOptions(opts []ConfigOption) error {
+	byNumber := make(map[int]string)
+	for i := range opts {
+		opts[i].setNumber(i)
+
+		n := opts[i].getNumber()
+		if other, ok := byNumber[n]; ok {
+			return fmt.Errorf("%w: --%s and --%s have number %d", ErrOptionNumberCollision, other, opts[i].call(), n)
+		}
+		byNumber[n] = opts[i].call()
+	}
+
+	return nil
+}
+
+// VerifyOptionNumbers checks that options have the expected numbers, keyed by option name.
+// It is intended for tests of extcap applications to detect accidental renumbering,
+// which makes Wireshark apply saved preferences to the wrong options.
+func VerifyOptionNumbers(opts []ConfigOption, expected map[string]int) error {
+	if err := numberOptions(opts); err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, opt := range opts {
+		want, ok := expected[opt.call()]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%w: --%s is not expected", ErrOptionRenumbered, opt.call()))
+			continue
+		}
+		if got := opt.getNumber(); got != want {
+			errs = append(errs, fmt.Errorf("%w: --%s has number %d, expected %d", ErrOptionRenumbered, opt.call(), got, want))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// optBuilder implements the modifiers common for all options, they return the option O for chaining.
+// V is the type of the option value.
+type optBuilder[O any, V any] struct {
+	cfg
+	self O
+}
+
+// Required sets if the option is required
+func (b *optBuilder[O, V]) Required(required bool) O {
+	b.required = required
+	return b.self
+}
+
+// MustSet makes the option required, it is a short form of Required(true)
+func (b *optBuilder[O, V]) MustSet() O {
+	return b.Required(true)
+}
+
+// Group sets option's group
+func (b *optBuilder[O, V]) Group(group string) O {
+	b.group = group
+	return b.self
+}
+
+// Tooltip sets option tooltip
+func (b *optBuilder[O, V]) Tooltip(tooltip string) O {
+	b.tooltipVal = tooltip
+	return b.self
+}
+
+// Number sets explicit number of the option, so reordering options does not break saved Wireshark preferences
+func (b *optBuilder[O, V]) Number(n int) O {
+	b.number = n
+	b.numberSet = true
+	return b.self
+}
+
+// DontSave prevents Wireshark from saving option value in the preferences
+func (b *optBuilder[O, V]) DontSave() O {
+	b.dontSave = true
+	return b.self
+}
+
+// Requires sets options which must be set when this option is set
+func (b *optBuilder[O, V]) Requires(names ...string) O {
+	b.requires = append(b.requires, names...)
+	return b.self
+}
+
+// ConflictsWith sets options which must not be set when this option is set
+func (b *optBuilder[O, V]) ConflictsWith(names ...string) O {
+	b.conflicts = append(b.conflicts, names...)
+	return b.self
+}
+
+// Validate adds function checking option value before capture is started
+func (b *optBuilder[O, V]) Validate(f func(V) error) O {
+	b.validators = append(b.validators, func(val interface{}) error {
+		if v, ok := val.(V); ok {
+			return f(v)
+		}
+		return nil
+	})
+	return b.self
+}
+
+// defaultBuilder adds the modifiers of the default value to optBuilder
+type defaultBuilder[O any, V any] struct {
+	optBuilder[O, V]
+	defaultValue V
+	defaultSet   bool
+}
+
+// Default sets default value of the option
+func (b *defaultBuilder[O, V]) Default(val V) O {
+	b.defaultValue = val
+	b.defaultSet = true
+	return b.self
+}
+
+// DefaultFunc sets function computing default value, it is evaluated every time Wireshark asks for the configuration
+func (b *defaultBuilder[O, V]) DefaultFunc(f func() (V, error)) O {
+	b.lazyDefault = func() error {
+		val, err := f()
+		if err != nil {
+			return err
+		}
+		b.Default(val)
+		return nil
+	}
+	return b.self
+}
+
+// EnvVar sets environment variable the default value is taken from, the value still can be changed in Wireshark GUI
+func (b *defaultBuilder[O, V]) EnvVar(name string) O {
+	b.envVar = name
+	b.envDefault = func(str string) error {
+		if err := parseValue(str, &b.defaultValue); err != nil {
+			return err
+		}
+		b.defaultSet = true
+		return nil
+	}
+	return b.self
+}
+
+// resolveDefault evaluates function set by DefaultFunc and applies environment variable set by EnvVar
+func (c *cfg) resolveDefault() error {
+	if c.lazyDefault != nil {
+		if err := c.lazyDefault(); err != nil {
+			return fmt.Errorf("option --%s: unable to compute default value: %w", c.callValue, err)
+		}
+	}
+	if c.envDefault != nil {
+		if str, ok := os.LookupEnv(c.envVar); ok {
+			if err := c.envDefault(str); err != nil {
+				return fmt.Errorf("option --%s: invalid value of %s: %w", c.callValue, c.envVar, err)
+			}
+		}
+	}
+	return nil
+}
+
+// parseValue parses string representation of option value, as passed by Wireshark, into val
+func parseValue(str string, val interface{}) error {
+	var err error
+	switch v := val.(type) {
+	case *int:
+		*v, err = strconv.Atoi(str)
+	case *uint:
+		var u uint64
+		u, err = strconv.ParseUint(str, 10, 0)
+		*v = uint(u)
+	case *int64:
+		*v, err = strconv.ParseInt(str, 10, 64)
+	case *float64:
+		*v, err = strconv.ParseFloat(str, 64)
+	case *string:
+		*v = str
+	case *bool:
+		*v, err = strconv.ParseBool(str)
+	case *time.Duration:
+		*v, err = time.ParseDuration(str)
+	case *time.Time:
+		var sec int64
+		sec, err = strconv.ParseInt(str, 10, 64)
+		*v = time.Unix(sec, 0)
+	default:
+		panic(fmt.Sprintf("unsupported option value type: %T", val))
+	}
+	return err
+}
+
+// convert turns value of the cli flag into value passed to StartCapture
+func (c *cfg) convert(val interface{}) (interface{}, error) {
+	return val, nil
+}
+
+// parse parses string value passed on the command line into val,
+// expected describes the format of the value in the error message
+func (c *cfg) parse(val interface{}, into interface{}, expected string) error {
+	str, _ := val.(string)
+	if err := parseValue(str, into); err != nil {
+		return &OptionError{Option: c.callValue, Value: str, Expected: expected}
+	}
+	return nil
+}
+
+// validate checks value passed on the command line before StartCapture is called
+func (c *cfg) validate(val interface{}) error {
+	for _, validator := range c.validators {
+		if err := validator(val); err != nil {
+			return fmt.Errorf("option --%s: %w: %w", c.callValue, ErrValueInvalid, err)
+		}
+	}
+	return nil
+}
+
+// checkRange verifies that val is within [min, max]
+func checkRange[T int | uint | int64 | float64](call string, val, min, max T) error {
+	if val < min {
+		return fmt.Errorf("option --%s: %w: %v is less than %v", call, ErrValueOutOfRange, val, min)
+	}
+	if val > max {
+		return fmt.Errorf("option --%s: %w: %v is greater than %v", call, ErrValueOutOfRange, val, max)
+	}
+	return nil
 }
 
 // ConfigIntegerOpt Integer option
 type ConfigIntegerOpt struct {
-	cfg
-	min          int
-	max          int
-	defaultValue int
+	defaultBuilder[*ConfigIntegerOpt, int]
+	min int
+	max int
 
-	rangeSet   bool
-	defaultSet bool
+	minSet bool
+	maxSet bool
 }
 
 // NewConfigIntegerOpt Create new integer option
 func NewConfigIntegerOpt(call, display string) *ConfigIntegerOpt {
 	opt := &ConfigIntegerOpt{}
+	opt.self = opt
 	opt.callValue = call
 	opt.displayVal = display
 
@@ -99,104 +399,374 @@ func (c *ConfigIntegerOpt) Range(min, max int) *ConfigIntegerOpt {
 		panic("in range max value should be greater min value")
 	}
 
+	return c.Min(min).Max(max)
+}
+
+// Min sets min value for option
+func (c *ConfigIntegerOpt) Min(min int) *ConfigIntegerOpt {
 	c.min = min
+	c.minSet = true
+	return c
+}
+
+// Max sets max value for option
+func (c *ConfigIntegerOpt) Max(max int) *ConfigIntegerOpt {
 	c.max = max
+	c.maxSet = true
+	return c
+}
 
-	c.rangeSet = true
+// bounds returns allowed range of option values, limits of the type are used for unset bounds
+func (c *ConfigIntegerOpt) bounds() (int, int) {
+	min, max := int(math.MinInt32), int(math.MaxInt32)
+	if c.minSet {
+		min = c.min
+	}
+	if c.maxSet {
+		max = c.max
+	}
+	return min, max
+}
 
-	return c
+// String implements stringer interface
+// Example output
+//
+//	arg {number=0}{call=--delay}{display=Time delay}{tooltip=Time delay between packages}{type=integer}{range=1,15}{required=true}
+func (c *ConfigIntegerOpt) String() string {
+	var params [][2]string
+
+	if c.minSet || c.maxSet {
+		min, max := c.bounds()
+		params = append(params, [2]string{"range", fmt.Sprintf("%d,%d", min, max)})
+	}
+
+	if c.defaultSet {
+		params = append(params, [2]string{"default", fmt.Sprintf("%d", c.defaultValue)})
+	}
+
+	return c.string("integer", params)
+}
+
+func (c *ConfigIntegerOpt) validate(val interface{}) error {
+	if v, ok := val.(int); ok {
+		min, max := c.bounds()
+		if err := checkRange(c.callValue, v, min, max); err != nil {
+			return err
+		}
+	}
+	return c.cfg.validate(val)
+}
+
+func (c *ConfigIntegerOpt) convert(val interface{}) (interface{}, error) {
+	var v int
+	err := c.parse(val, &v, "integer")
+	return v, err
+}
+
+// ConfigUnsignedOpt Unsigned integer option
+// Wireshark treats unsigned options as 32-bit values, so larger values are rejected unless Max is set.
+type ConfigUnsignedOpt struct {
+	defaultBuilder[*ConfigUnsignedOpt, uint]
+	min uint
+	max uint
+
+	minSet bool
+	maxSet bool
+}
+
+// NewConfigUnsignedOpt Create new unsigned integer option
+func NewConfigUnsignedOpt(call, display string) *ConfigUnsignedOpt {
+	opt := &ConfigUnsignedOpt{}
+	opt.self = opt
+	opt.callValue = call
+	opt.displayVal = display
+
+	return opt
 }
 
-// Default sets default value for INTEGER option
-func (c *ConfigIntegerOpt) Default(val int) *ConfigIntegerOpt {
-	c.defaultValue = val
-	c.defaultSet = true
+// Range sets min and max value for option
+func (c *ConfigUnsignedOpt) Range(min, max uint) *ConfigUnsignedOpt {
+	if min >= max {
+		panic("in range max value should be greater min value")
+	}
+
+	return c.Min(min).Max(max)
+}
+
+// Min sets min value for option
+func (c *ConfigUnsignedOpt) Min(min uint) *ConfigUnsignedOpt {
+	c.min = min
+	c.minSet = true
 	return c
 }
 
-// Required sets option required
-func (c *ConfigIntegerOpt) Required(val bool) *ConfigIntegerOpt {
-	c.required = val
+// Max sets max value for option
+func (c *ConfigUnsignedOpt) Max(max uint) *ConfigUnsignedOpt {
+	c.max = max
+	c.maxSet = true
 	return c
 }
 
-// Group sets option's group
-func (c *ConfigIntegerOpt) Group(group string) *ConfigIntegerOpt {
-	c.group = group
+// bounds returns allowed range of option values, limits of the type are used for unset bounds
+func (c *ConfigUnsignedOpt) bounds() (uint, uint) {
+	min, max := uint(0), uint(math.MaxUint32)
+	if c.minSet {
+		min = c.min
+	}
+	if c.maxSet {
+		max = c.max
+	}
+	return min, max
+}
+
+// String implements stringer interface
+// Example output
+//
+//	arg {number=0}{call=--port}{display=Remote port}{type=unsigned}{range=1,65535}{default=22}
+func (c *ConfigUnsignedOpt) String() string {
+	var params [][2]string
+
+	if c.minSet || c.maxSet {
+		min, max := c.bounds()
+		params = append(params, [2]string{"range", fmt.Sprintf("%d,%d", min, max)})
+	}
+
+	if c.defaultSet {
+		params = append(params, [2]string{"default", fmt.Sprintf("%d", c.defaultValue)})
+	}
+
+	return c.string("unsigned", params)
+}
+
+func (c *ConfigUnsignedOpt) validate(val interface{}) error {
+	if v, ok := val.(uint); ok {
+		min, max := c.bounds()
+		if err := checkRange(c.callValue, v, min, max); err != nil {
+			return err
+		}
+	}
+	return c.cfg.validate(val)
+}
+
+func (c *ConfigUnsignedOpt) convert(val interface{}) (interface{}, error) {
+	var v uint
+	err := c.parse(val, &v, "unsigned integer")
+	return v, err
+}
+
+// ConfigLongOpt Long (64-bit) integer option
+type ConfigLongOpt struct {
+	defaultBuilder[*ConfigLongOpt, int64]
+	min int64
+	max int64
+
+	minSet bool
+	maxSet bool
+}
+
+// NewConfigLongOpt Create new long integer option
+func NewConfigLongOpt(call, display string) *ConfigLongOpt {
+	opt := &ConfigLongOpt{}
+	opt.self = opt
+	opt.callValue = call
+	opt.displayVal = display
+
+	return opt
+}
+
+// Range sets min and max value for option
+func (c *ConfigLongOpt) Range(min, max int64) *ConfigLongOpt {
+	if min >= max {
+		panic("in range max value should be greater min value")
+	}
+
+	return c.Min(min).Max(max)
+}
+
+// Min sets min value for option
+func (c *ConfigLongOpt) Min(min int64) *ConfigLongOpt {
+	c.min = min
+	c.minSet = true
 	return c
 }
 
-// Tooltip sets option tooltip
-func (c *ConfigIntegerOpt) Tooltip(tooltip string) *ConfigIntegerOpt {
-	c.tooltipVal = tooltip
+// Max sets max value for option
+func (c *ConfigLongOpt) Max(max int64) *ConfigLongOpt {
+	c.max = max
+	c.maxSet = true
 	return c
 }
 
+// bounds returns allowed range of option values, limits of the type are used for unset bounds
+func (c *ConfigLongOpt) bounds() (int64, int64) {
+	min, max := int64(math.MinInt64), int64(math.MaxInt64)
+	if c.minSet {
+		min = c.min
+	}
+	if c.maxSet {
+		max = c.max
+	}
+	return min, max
+}
+
 // String implements stringer interface
 // Example output
 //
-//	arg {number=0}{call=--delay}{display=Time delay}{tooltip=Time delay between packages}{type=integer}{range=1,15}{required=true}
-func (c *ConfigIntegerOpt) String() string {
+//	arg {number=0}{call=--count}{display=Packet count}{type=long}{default=10000000000}
+func (c *ConfigLongOpt) String() string {
 	var params [][2]string
 
-	if c.rangeSet {
-		params = append(params, [2]string{"range", fmt.Sprintf("%d,%d", c.min, c.max)})
+	if c.minSet || c.maxSet {
+		min, max := c.bounds()
+		params = append(params, [2]string{"range", fmt.Sprintf("%d,%d", min, max)})
 	}
 
 	if c.defaultSet {
 		params = append(params, [2]string{"default", fmt.Sprintf("%d", c.defaultValue)})
 	}
 
-	return c.string("integer", params)
+	return c.string("long", params)
+}
+
+func (c *ConfigLongOpt) validate(val interface{}) error {
+	if v, ok := val.(int64); ok {
+		min, max := c.bounds()
+		if err := checkRange(c.callValue, v, min, max); err != nil {
+			return err
+		}
+	}
+	return c.cfg.validate(val)
+}
+
+func (c *ConfigLongOpt) convert(val interface{}) (interface{}, error) {
+	var v int64
+	err := c.parse(val, &v, "long integer")
+	return v, err
+}
+
+// ConfigDoubleOpt Double (floating point) option
+type ConfigDoubleOpt struct {
+	defaultBuilder[*ConfigDoubleOpt, float64]
+	min float64
+	max float64
+
+	minSet bool
+	maxSet bool
+}
+
+// NewConfigDoubleOpt Create new double option
+func NewConfigDoubleOpt(call, display string) *ConfigDoubleOpt {
+	opt := &ConfigDoubleOpt{}
+	opt.self = opt
+	opt.callValue = call
+	opt.displayVal = display
+
+	return opt
+}
+
+// Range sets min and max value for option
+func (c *ConfigDoubleOpt) Range(min, max float64) *ConfigDoubleOpt {
+	if min >= max {
+		panic("in range max value should be greater min value")
+	}
+
+	return c.Min(min).Max(max)
+}
+
+// Min sets min value for option
+func (c *ConfigDoubleOpt) Min(min float64) *ConfigDoubleOpt {
+	c.min = min
+	c.minSet = true
+	return c
+}
+
+// Max sets max value for option
+func (c *ConfigDoubleOpt) Max(max float64) *ConfigDoubleOpt {
+	c.max = max
+	c.maxSet = true
+	return c
+}
+
+// bounds returns allowed range of option values, limits of the type are used for unset bounds
+func (c *ConfigDoubleOpt) bounds() (float64, float64) {
+	min, max := float64(-math.MaxFloat64), float64(math.MaxFloat64)
+	if c.minSet {
+		min = c.min
+	}
+	if c.maxSet {
+		max = c.max
+	}
+	return min, max
+}
+
+// String implements stringer interface
+// Example output
+//
+//	arg {number=0}{call=--rate}{display=Sampling rate}{type=double}{range=0.5,10}{default=1.5}
+func (c *ConfigDoubleOpt) String() string {
+	var params [][2]string
+
+	if c.minSet || c.maxSet {
+		min, max := c.bounds()
+		params = append(params, [2]string{"range", formatFloat(min) + "," + formatFloat(max)})
+	}
+
+	if c.defaultSet {
+		params = append(params, [2]string{"default", formatFloat(c.defaultValue)})
+	}
+
+	return c.string("double", params)
+}
+
+func (c *ConfigDoubleOpt) validate(val interface{}) error {
+	if v, ok := val.(float64); ok {
+		min, max := c.bounds()
+		if err := checkRange(c.callValue, v, min, max); err != nil {
+			return err
+		}
+	}
+	return c.cfg.validate(val)
+}
+
+func (c *ConfigDoubleOpt) convert(val interface{}) (interface{}, error) {
+	var v float64
+	err := c.parse(val, &v, "floating point number")
+	return v, err
+}
+
+func formatFloat(val float64) string {
+	return strconv.FormatFloat(val, 'g', -1, 64)
 }
 
 // ConfigStringOpt implements ConfigOption interface
 type ConfigStringOpt struct {
-	cfg
-	placeholder  string
-	validation   *regexp.Regexp
-	required     bool
-	defaultValue string
-	defaultSet   bool
+	defaultBuilder[*ConfigStringOpt, string]
+	placeholder string
+	validation  *regexp.Regexp
+	fullMatch   *regexp.Regexp
 }
 
 // NewConfigStringOpt Create new STRING option
 func NewConfigStringOpt(call, display string) *ConfigStringOpt {
 	opt := &ConfigStringOpt{}
+	opt.self = opt
 	opt.callValue = call
 	opt.displayVal = display
 
 	return opt
 }
 
-// Default sets default value for STRING option
-func (c *ConfigStringOpt) Default(val string) *ConfigStringOpt {
-	c.defaultValue = val
-	c.defaultSet = true
-	return c
-}
-
-// Placeholder sets option tooltip
+// Placeholder sets hint text shown while the field is empty
 func (c *ConfigStringOpt) Placeholder(str string) *ConfigStringOpt {
 	c.placeholder = str
 	return c
 }
 
-// Required sets option required
-func (c *ConfigStringOpt) Required(val bool) *ConfigStringOpt {
-	c.required = val
-	return c
-}
-
 // Validation sets option validation
+// Wireshark requires the whole value to match the expression, so does the library when the value is passed on the command line.
 func (c *ConfigStringOpt) Validation(str string) *ConfigStringOpt {
 	c.validation = regexp.MustCompile(str)
-	return c
-}
-
-// Tooltip sets option tooltip
-func (c *ConfigStringOpt) Tooltip(tooltip string) *ConfigStringOpt {
-	c.tooltipVal = tooltip
+	c.fullMatch = regexp.MustCompile("^(?:" + str + ")$")
 	return c
 }
 
@@ -220,57 +790,386 @@ func (c *ConfigStringOpt) String() string {
 	return c.string("string", params)
 }
 
+func (c *ConfigStringOpt) validate(val interface{}) error {
+	if v, ok := val.(string); ok && v != "" && c.fullMatch != nil && !c.fullMatch.MatchString(v) {
+		return fmt.Errorf("option --%s: %w: %q does not match %s", c.callValue, ErrValueInvalid, v, c.validation)
+	}
+	return c.cfg.validate(val)
+}
+
+// ConfigDurationOpt implements ConfigOption interface
+// Duration is shown as a string option accepting Go-style durations ("30s", "5m"),
+// the value passed to StartCapture is time.Duration.
+type ConfigDurationOpt struct {
+	defaultBuilder[*ConfigDurationOpt, time.Duration]
+}
+
+// durationValidation matches durations accepted by time.ParseDuration
+const durationValidation = `0|([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+`
+
+// NewConfigDurationOpt Create new DURATION option
+func NewConfigDurationOpt(call, display string) *ConfigDurationOpt {
+	opt := &ConfigDurationOpt{}
+	opt.self = opt
+	opt.callValue = call
+	opt.displayVal = display
+
+	return opt
+}
+
+// String implements string interface
+// arg {number=0}{call=--duration}{display=Capture duration}{type=string}{validation=0|([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+}{default=30s}
+func (c *ConfigDurationOpt) String() string {
+	params := [][2]string{{"validation", durationValidation}}
+
+	if c.defaultSet {
+		params = append(params, [2]string{"default", c.defaultValue.String()})
+	}
+
+	return c.string("string", params)
+}
+
+func (c *ConfigDurationOpt) convert(val interface{}) (interface{}, error) {
+	if val == "" {
+		return time.Duration(0), nil
+	}
+	var v time.Duration
+	err := c.parse(val, &v, "duration like 30s or 5m")
+	return v, err
+}
+
 // ConfigBoolOpt implements ConfigOption interface
 type ConfigBoolOpt struct {
-	cfg
-	validation   *regexp.Regexp
-	required     bool
-	defaultValue bool
-	defaultSet   bool
+	defaultBuilder[*ConfigBoolOpt, bool]
+	validation *regexp.Regexp
 }
 
 // NewConfigBoolOpt Create new BOOL option
 func NewConfigBoolOpt(call, display string) *ConfigBoolOpt {
 	opt := &ConfigBoolOpt{}
+	opt.self = opt
 	opt.callValue = call
 	opt.displayVal = display
 
 	return opt
 }
 
-// Default sets default value option
-func (c *ConfigBoolOpt) Default(val bool) *ConfigBoolOpt {
-	c.defaultValue = val
-	c.defaultSet = true
+// String implements string interface
+// arg {number=2}{call=--verify}{display=Verify}{tooltip=Verify package content}{type=boolflag}
+func (c *ConfigBoolOpt) String() string {
+	var params [][2]string
+
+	if c.defaultSet {
+		params = append(params, [2]string{"default", fmt.Sprintf("%t", c.defaultValue)})
+	}
+
+	return c.string("boolflag", params)
+}
+
+// ConfigPasswordOpt implements ConfigOption interface
+// Value of password option is masked in Wireshark GUI and is never saved to the preferences.
+// The library passes the value to StartCapture as is, but never prints it.
+type ConfigPasswordOpt struct {
+	optBuilder[*ConfigPasswordOpt, string]
+	placeholder string
+}
+
+// NewConfigPasswordOpt Create new PASSWORD option
+func NewConfigPasswordOpt(call, display string) *ConfigPasswordOpt {
+	opt := &ConfigPasswordOpt{}
+	opt.self = opt
+	opt.callValue = call
+	opt.displayVal = display
+
+	return opt
+}
+
+// EnvVar sets environment variable the value is taken from when it is not passed on the command line.
+// Unlike other options, the value is never shown in Wireshark GUI as default.
+func (c *ConfigPasswordOpt) EnvVar(name string) *ConfigPasswordOpt {
+	c.envVar = name
 	return c
 }
 
-// Tooltip sets option tooltip
-func (c *ConfigBoolOpt) Tooltip(tooltip string) *ConfigBoolOpt {
-	c.tooltipVal = tooltip
+// Placeholder sets hint text shown while the field is empty
+func (c *ConfigPasswordOpt) Placeholder(str string) *ConfigPasswordOpt {
+	c.placeholder = str
 	return c
 }
 
-// Required sets option required
-func (c *ConfigBoolOpt) Required(val bool) *ConfigBoolOpt {
-	c.required = val
+// String implements string interface
+// arg {number=0}{call=--password}{display=Password}{type=password}
+func (c *ConfigPasswordOpt) String() string {
+	var params [][2]string
+
+	if c.placeholder != "" {
+		params = append(params, [2]string{"placeholder", c.placeholder})
+	}
+
+	return c.string("password", params)
+}
+
+// ConfigTimestampOpt implements ConfigOption interface
+// Wireshark passes timestamp as number of seconds since epoch, the value passed to StartCapture is time.Time.
+type ConfigTimestampOpt struct {
+	defaultBuilder[*ConfigTimestampOpt, time.Time]
+}
+
+// NewConfigTimestampOpt Create new TIMESTAMP option
+func NewConfigTimestampOpt(call, display string) *ConfigTimestampOpt {
+	opt := &ConfigTimestampOpt{}
+	opt.self = opt
+	opt.callValue = call
+	opt.displayVal = display
+
+	return opt
+}
+
+// String implements string interface
+// arg {number=0}{call=--ts}{display=Start Time}{type=timestamp}
+func (c *ConfigTimestampOpt) String() string {
+	var params [][2]string
+
+	if c.defaultSet {
+		params = append(params, [2]string{"default", fmt.Sprintf("%d", c.defaultValue.Unix())})
+	}
+
+	return c.string("timestamp", params)
+}
+
+func (c *ConfigTimestampOpt) convert(val interface{}) (interface{}, error) {
+	var v time.Time
+	err := c.parse(val, &v, "number of seconds since epoch")
+	return v, err
+}
+
+// OptValue represents single value of selector-like option
+type OptValue struct {
+	Value   string `json:"value"`
+	Display string `json:"display"`
+	// Parent is the value this value is nested under, used by selector and multicheck options to present trees
+	Parent string `json:"parent,omitempty"`
+	// Default marks value selected (or checked) when the option is shown
+	Default bool `json:"default,omitempty"`
+}
+
+// Format to string in format
+// value {arg=3}{value=if1}{display=Remote1}{parent=dev1}{default=true}
+func (v OptValue) string(arg int) string {
+	str := fmt.Sprintf("value {arg=%d}{value=%s}{display=%s}", arg, escapeValue(v.Value), escapeText(v.Display))
+	if v.Parent != "" {
+		str += fmt.Sprintf("{parent=%s}", escapeValue(v.Parent))
+	}
+	if v.Default {
+		str += "{default=true}"
+	}
+	return str
+}
+
+// defaultValues returns values marked as default
+func defaultValues(values []OptValue) []string {
+	var defaults []string
+	for _, v := range values {
+		if v.Default {
+			defaults = append(defaults, v.Value)
+		}
+	}
+	return defaults
+}
+
+// ValueNode is a value with nested values, used to build hierarchies of option values
+type ValueNode struct {
+	OptValue
+	Children []ValueNode
+}
+
+// Node creates value with nested values
+func Node(value, display string, children ...ValueNode) ValueNode {
+	return ValueNode{OptValue: OptValue{Value: value, Display: display}, Children: children}
+}
+
+// Flatten turns hierarchy of values into list of values with Parent set, parents precede their children
+//
+//	Flatten(Node("dev1", "Device 1", Node("ch1", "Channel 1"), Node("ch2", "Channel 2")))
+func Flatten(nodes ...ValueNode) []OptValue {
+	var values []OptValue
+	for _, node := range nodes {
+		values = append(values, node.OptValue)
+		for _, child := range Flatten(node.Children...) {
+			if child.Parent == "" {
+				child.Parent = node.Value
+			}
+			values = append(values, child)
+		}
+	}
+	return values
+}
+
+// ConfigSelectorOpt implements ConfigOption interface
+// User can pick one of provided values.
+type ConfigSelectorOpt struct {
+	optBuilder[*ConfigSelectorOpt, string]
+	placeholder string
+	optValues   []OptValue
+	reload      bool
+}
+
+// NewConfigSelectorOpt Create new SELECTOR option
+func NewConfigSelectorOpt(call, display string) *ConfigSelectorOpt {
+	opt := &ConfigSelectorOpt{}
+	opt.self = opt
+	opt.callValue = call
+	opt.displayVal = display
+
+	return opt
+}
+
+// Values sets values user can pick from
+func (c *ConfigSelectorOpt) Values(values ...OptValue) *ConfigSelectorOpt {
+	c.optValues = values
+	return c
+}
+
+// Reload enables reload button in Wireshark GUI, the values are refreshed by App.ReloadOption
+// Placeholder is used as the label of the button.
+func (c *ConfigSelectorOpt) Reload() *ConfigSelectorOpt {
+	c.reload = true
+	return c
+}
+
+// Placeholder sets label of the reload button
+func (c *ConfigSelectorOpt) Placeholder(str string) *ConfigSelectorOpt {
+	c.placeholder = str
 	return c
 }
 
 // String implements string interface
-// arg {number=2}{call=--verify}{display=Verify}{tooltip=Verify package content}{type=boolflag}
-func (c *ConfigBoolOpt) String() string {
+// Example output
+//
+//	arg {number=0}{call=--remote}{display=Remote Channel}{type=selector}{placeholder=Load interfaces...}{reload=true}
+//	value {arg=0}{value=if1}{display=Remote1}
+//	value {arg=0}{value=if2}{display=Remote2}
+func (c *ConfigSelectorOpt) String() string {
+	var params [][2]string
+
+	if c.placeholder != "" {
+		params = append(params, [2]string{"placeholder", c.placeholder})
+	}
+
+	if c.reload {
+		params = append(params, [2]string{"reload", "true"})
+	}
+
+	return c.string("selector", params) + c.values(c.optValues)
+}
+
+// ConfigEditSelectorOpt implements ConfigOption interface
+// User can either pick one of provided values or type any other value.
+type ConfigEditSelectorOpt struct {
+	defaultBuilder[*ConfigEditSelectorOpt, string]
+	placeholder string
+	optValues   []OptValue
+	reload      bool
+}
+
+// NewConfigEditSelectorOpt Create new EDITSELECTOR option
+func NewConfigEditSelectorOpt(call, display string) *ConfigEditSelectorOpt {
+	opt := &ConfigEditSelectorOpt{}
+	opt.self = opt
+	opt.callValue = call
+	opt.displayVal = display
+
+	return opt
+}
+
+// Values sets values user can pick from
+func (c *ConfigEditSelectorOpt) Values(values ...OptValue) *ConfigEditSelectorOpt {
+	c.optValues = values
+	return c
+}
+
+// Reload enables reload button in Wireshark GUI, the values are refreshed by App.ReloadOption
+// Placeholder is used as the label of the button.
+func (c *ConfigEditSelectorOpt) Reload() *ConfigEditSelectorOpt {
+	c.reload = true
+	return c
+}
+
+// Placeholder sets hint text shown while the field is empty
+func (c *ConfigEditSelectorOpt) Placeholder(str string) *ConfigEditSelectorOpt {
+	c.placeholder = str
+	return c
+}
+
+// String implements string interface
+// Example output
+//
+//	arg {number=0}{call=--host}{display=Remote host}{type=editselector}
+//	value {arg=0}{value=10.0.0.1}{display=Router}
+//	value {arg=0}{value=10.0.0.2}{display=Switch}
+func (c *ConfigEditSelectorOpt) String() string {
 	var params [][2]string
 
+	if c.placeholder != "" {
+		params = append(params, [2]string{"placeholder", c.placeholder})
+	}
+
 	if c.defaultSet {
-		params = append(params, [2]string{"default", fmt.Sprintf("%t", c.defaultValue)})
+		params = append(params, [2]string{"default", c.defaultValue})
 	}
 
-	return c.string("boolflag", params)
+	if c.reload {
+		params = append(params, [2]string{"reload", "true"})
+	}
+
+	return c.string("editselector", params) + c.values(c.optValues)
+}
+
+// ConfigMulticheckOpt implements ConfigOption interface
+// User can check any number of provided values, the value passed to StartCapture is []string.
+type ConfigMulticheckOpt struct {
+	optBuilder[*ConfigMulticheckOpt, []string]
+	optValues []OptValue
+}
+
+// NewConfigMulticheckOpt Create new MULTICHECK option
+func NewConfigMulticheckOpt(call, display string) *ConfigMulticheckOpt {
+	opt := &ConfigMulticheckOpt{}
+	opt.self = opt
+	opt.callValue = call
+	opt.displayVal = display
+
+	return opt
+}
+
+// Values sets values user can check, use Flatten to build a tree of values
+func (c *ConfigMulticheckOpt) Values(values ...OptValue) *ConfigMulticheckOpt {
+	c.optValues = values
+	return c
+}
+
+// String implements string interface
+// Example output
+//
+//	arg {number=0}{call=--channels}{display=Channels}{type=multicheck}
+//	value {arg=0}{value=dev1}{display=Device 1}
+//	value {arg=0}{value=ch1}{display=Channel 1}{parent=dev1}
+func (c *ConfigMulticheckOpt) String() string {
+	return c.string("multicheck", nil) + c.values(c.optValues)
+}
+
+// convert splits comma separated list of checked values passed by Wireshark
+func (c *ConfigMulticheckOpt) convert(val interface{}) (interface{}, error) {
+	str, ok := val.(string)
+	if !ok {
+		return val, nil
+	}
+	if str == "" {
+		return []string(nil), nil
+	}
+	return strings.Split(str, ","), nil
 }
 
 // Need implement
 // fileselect
-// selector
 // radio
-// multicheck
@@ -0,0 +1,547 @@
+package extcap
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+// ControlCommand is the command of a control message, see
+// https://www.wireshark.org/docs/wsdg_html_chunked/ChCaptureExtcap.html#_toolbar_controls
+type ControlCommand uint8
+
+const (
+	// ControlInitialized is sent by Wireshark when all initial control values are sent
+	ControlInitialized ControlCommand = iota
+	// ControlSet sets the value of a control
+	ControlSet
+	// ControlAdd adds a value to a selector or a message to a logger
+	ControlAdd
+	// ControlRemove removes a value from a selector
+	ControlRemove
+	// ControlEnable enables a control
+	ControlEnable
+	// ControlDisable disables a control
+	ControlDisable
+	// ControlStatusbar shows a message in the status bar
+	ControlStatusbar
+	// ControlInformationMessage shows an information dialog
+	ControlInformationMessage
+	// ControlWarningMessage shows a warning dialog
+	ControlWarningMessage
+	// ControlErrorMessage shows an error dialog
+	ControlErrorMessage
+)
+
+// ControlMessage is a single message exchanged with Wireshark over the control pipes
+type ControlMessage struct {
+	// Control is the number of the control the message is for
+	Control uint8
+	Command ControlCommand
+	Payload []byte
+}
+
+// ControlChannel is the connection to the Wireshark interface toolbar.
+// Incoming messages are read in background and delivered by Messages,
+// outgoing messages are written one by one in the order they are sent.
+// Messages sent before Wireshark reports it is initialized are buffered and written after that.
+type ControlChannel struct {
+	in  io.ReadCloser
+	out io.WriteCloser
+
+	// controls with handlers, by number
+	controls map[int]ToolbarControl
+	// handlers registered with Handle, by control number
+	handlers   map[uint8]func(ControlMessage)
+	handlersMu sync.RWMutex
+	// cancel stops the capture
+	cancel context.CancelCauseFunc
+
+	messages chan ControlMessage
+	outgoing chan outgoingMessage
+	done     chan struct{}
+
+	// queue holds received messages until the dispatch loop passes them on,
+	// it is not bounded so the reader never waits for handlers
+	queue        []queuedMessage
+	queueMu      sync.Mutex
+	queued       chan struct{}
+	readDone     chan struct{}
+	dispatchDone chan struct{}
+
+	initialized chan struct{}
+	initOnce    sync.Once
+
+	readErr   error
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// controlQueueSize is the number of messages buffered until Wireshark is initialized
+const controlQueueSize = 256
+
+type queuedMessage struct {
+	msg     ControlMessage
+	barrier chan struct{}
+}
+
+type outgoingMessage struct {
+	msg     ControlMessage
+	barrier bool
+	result  chan error
+}
+
+// newControlChannel starts reader and writer loops on the control pipes.
+// Messages for the controls are passed to their handlers, the rest is delivered by Messages.
+func newControlChannel(in io.ReadCloser, out io.WriteCloser, controls []ToolbarControl, cancel context.CancelCauseFunc) *ControlChannel {
+	c := &ControlChannel{
+		in:       in,
+		out:      out,
+		controls: make(map[int]ToolbarControl),
+		handlers: make(map[uint8]func(ControlMessage)),
+		cancel:   cancel,
+		messages: make(chan ControlMessage, 16),
+		outgoing: make(chan outgoingMessage),
+		done:     make(chan struct{}),
+		queued:   make(chan struct{}, 1),
+		readDone: make(chan struct{}),
+
+		dispatchDone: make(chan struct{}),
+
+		initialized: make(chan struct{}),
+	}
+
+	for _, control := range controls {
+		c.controls[control.getNumber()] = control
+		control.attach(c)
+	}
+
+	c.wg.Add(3)
+	go c.readLoop()
+	go c.dispatchLoop()
+	go c.writeLoop()
+
+	return c
+}
+
+// Messages returns messages received from Wireshark which are not handled by the controls,
+// the channel is closed when the control-in pipe is closed
+func (c *ControlChannel) Messages() <-chan ControlMessage {
+	return c.messages
+}
+
+// Handle registers handler for messages of the control, it takes precedence over the handler of the control itself.
+// Nil handler removes the registration. Handlers are called one at a time in the order the messages arrive,
+// so no other control message is handled until the handler returns. Handler may call Send,
+// the initialized message is still received while it waits.
+func (c *ControlChannel) Handle(control uint8, handler func(msg ControlMessage)) {
+	c.handlersMu.Lock()
+	defer c.handlersMu.Unlock()
+
+	if handler == nil {
+		delete(c.handlers, control)
+		return
+	}
+	c.handlers[control] = handler
+}
+
+// Initialized returns channel closed when Wireshark has sent initial values of the controls
+// and is ready to receive control updates
+func (c *ControlChannel) Initialized() <-chan struct{} {
+	return c.initialized
+}
+
+// Err returns the error which stopped reading from the control-in pipe, it matches ErrHostClosed.
+// It is valid once the Messages channel is closed, nil if the channel was closed by Close.
+func (c *ControlChannel) Err() error {
+	return c.readErr
+}
+
+// Send writes message to Wireshark and waits until it is written. It is safe to call from multiple goroutines,
+// messages are written whole, one at a time. Before Wireshark is initialized up to 256 messages are buffered
+// and Send returns immediately, error of writing buffered message is returned by the next Send.
+// When the buffer is full, Send blocks until Wireshark is initialized.
+func (c *ControlChannel) Send(control uint8, command ControlCommand, payload []byte) error {
+	req := outgoingMessage{
+		msg:    ControlMessage{Control: control, Command: command, Payload: payload},
+		result: make(chan error, 1),
+	}
+
+	select {
+	case c.outgoing <- req:
+	case <-c.done:
+		return ErrControlClosed
+	}
+
+	return <-req.result
+}
+
+// Close stops the loops and closes both pipes
+func (c *ControlChannel) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.done)
+		err = errors.Join(c.out.Close(), c.in.Close())
+		c.wg.Wait()
+	})
+	return err
+}
+
+func (c *ControlChannel) readLoop() {
+	defer c.wg.Done()
+	defer close(c.readDone)
+
+	for {
+		msg, err := ReadControlMessage(c.in)
+		if err != nil {
+			select {
+			case <-c.done:
+				// pipe was closed by Close
+			default:
+				c.readErr = ErrHostClosed
+				if !errors.Is(err, io.EOF) {
+					c.readErr = fmt.Errorf("%w: %w", ErrHostClosed, err)
+				}
+				// Wireshark went away, there is nobody to capture for
+				if c.cancel != nil {
+					c.cancel(c.readErr)
+				}
+			}
+			return
+		}
+
+		if msg.Command == ControlInitialized {
+			c.initOnce.Do(func() { close(c.initialized) })
+			continue
+		}
+
+		c.enqueue(queuedMessage{msg: msg})
+	}
+}
+
+func (c *ControlChannel) enqueue(item queuedMessage) {
+	c.queueMu.Lock()
+	c.queue = append(c.queue, item)
+	c.queueMu.Unlock()
+
+	select {
+	case c.queued <- struct{}{}:
+	default:
+	}
+}
+
+// dispatchLoop passes received messages to the handlers or to Messages, apart from the reader,
+// so handlers waiting for Wireshark to be initialized do not stop the initialized message
+func (c *ControlChannel) dispatchLoop() {
+	defer c.wg.Done()
+	defer close(c.dispatchDone)
+	defer close(c.messages)
+
+	for {
+		readDone := false
+		select {
+		case <-c.queued:
+		case <-c.readDone:
+			readDone = true
+		case <-c.done:
+			return
+		}
+
+		c.queueMu.Lock()
+		items := c.queue
+		c.queue = nil
+		c.queueMu.Unlock()
+
+		for _, item := range items {
+			if item.barrier != nil {
+				close(item.barrier)
+				continue
+			}
+			if c.dispatch(item.msg) {
+				continue
+			}
+			select {
+			case c.messages <- item.msg:
+			case <-c.done:
+				return
+			}
+		}
+
+		// the reader has stopped before the queue was taken, nothing more comes
+		if readDone {
+			return
+		}
+	}
+}
+
+// dispatched waits until the messages received so far are dispatched
+func (c *ControlChannel) dispatched() {
+	barrier := make(chan struct{})
+	c.enqueue(queuedMessage{barrier: barrier})
+
+	select {
+	case <-barrier:
+	case <-c.dispatchDone:
+	}
+}
+
+// dispatch passes message to the registered handler or the control, it returns false if nobody handled it
+func (c *ControlChannel) dispatch(msg ControlMessage) bool {
+	c.handlersMu.RLock()
+	handler, ok := c.handlers[msg.Control]
+	c.handlersMu.RUnlock()
+	if ok {
+		handler(msg)
+		return true
+	}
+
+	control, ok := c.controls[int(msg.Control)]
+	return ok && control.handle(c, msg)
+}
+
+// restoreDefaults sends default values of all controls to Wireshark
+func (c *ControlChannel) restoreDefaults() {
+	numbers := make([]int, 0, len(c.controls))
+	for n := range c.controls {
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
+
+	for _, n := range numbers {
+		if payload, ok := c.controls[n].defaultPayload(); ok {
+			if err := c.Send(uint8(n), ControlSet, payload); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (c *ControlChannel) writeLoop() {
+	defer c.wg.Done()
+
+	var pending []ControlMessage
+	var pendingErr error
+	initialized := c.initialized
+	// set to nil while the buffer is full, so senders wait for Wireshark to get initialized
+	outgoing := c.outgoing
+	for {
+		select {
+		case req := <-outgoing:
+			if initialized != nil {
+				// buffered messages go first if Wireshark got initialized meanwhile
+				select {
+				case <-initialized:
+					initialized = nil
+					pendingErr = c.flush(pending)
+					pending = nil
+				default:
+				}
+			}
+			if req.barrier {
+				req.result <- nil
+				continue
+			}
+			if initialized != nil {
+				pending = append(pending, req.msg)
+				req.result <- nil
+				if len(pending) >= controlQueueSize {
+					outgoing = nil
+				}
+				continue
+			}
+			req.result <- errors.Join(pendingErr, WriteControlMessage(c.out, req.msg))
+			pendingErr = nil
+		case <-initialized:
+			// nil channel is never ready, so this case runs once
+			initialized = nil
+			pendingErr = c.flush(pending)
+			pending = nil
+			outgoing = c.outgoing
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// flush writes buffered messages, it returns the first error
+func (c *ControlChannel) flush(pending []ControlMessage) error {
+	var firstErr error
+	for _, msg := range pending {
+		if err := WriteControlMessage(c.out, msg); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// barrier returns once all messages sent before it are processed by the writer
+func (c *ControlChannel) barrier() error {
+	req := outgoingMessage{barrier: true, result: make(chan error, 1)}
+	select {
+	case c.outgoing <- req:
+	case <-c.done:
+		return ErrControlClosed
+	}
+	return <-req.result
+}
+
+// Control messages are framed as sync pipe packets
+//
+//	'T' | length (3 bytes, big endian) | control number | command | payload
+//
+// where length covers control number, command and payload.
+const (
+	controlSyncIndicator = 'T'
+	controlMaxLength     = 1<<24 - 1
+)
+
+// ReadControlMessage reads single control message framed as sync pipe packet
+func ReadControlMessage(r io.Reader) (ControlMessage, error) {
+	var header [6]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return ControlMessage{}, err
+	}
+	if header[0] != controlSyncIndicator {
+		return ControlMessage{}, fmt.Errorf("%w: unexpected sync indicator %q", ErrInvalidControlMessage, header[0])
+	}
+
+	length := int(header[1])<<16 | int(header[2])<<8 | int(header[3])
+	if length < 2 {
+		// control number and command are mandatory
+		return ControlMessage{}, fmt.Errorf("%w: length %d is too short", ErrInvalidControlMessage, length)
+	}
+
+	msg := ControlMessage{Control: header[4], Command: ControlCommand(header[5])}
+	if length > 2 {
+		msg.Payload = make([]byte, length-2)
+		if _, err := io.ReadFull(r, msg.Payload); err != nil {
+			return ControlMessage{}, err
+		}
+	}
+
+	return msg, nil
+}
+
+// WriteControlMessage writes control message framed as sync pipe packet in a single Write call
+func WriteControlMessage(w io.Writer, msg ControlMessage) error {
+	length := len(msg.Payload) + 2
+	if length > controlMaxLength {
+		return fmt.Errorf("%w: payload of %d bytes is too long", ErrInvalidControlMessage, len(msg.Payload))
+	}
+
+	frame := make([]byte, 4, 6+len(msg.Payload))
+	binary.BigEndian.PutUint32(frame, uint32(length))
+	frame[0] = controlSyncIndicator
+	frame = append(frame, msg.Control, byte(msg.Command))
+	frame = append(frame, msg.Payload...)
+
+	_, err := w.Write(frame)
+	return err
+}
+
+// openControlIn opens the control-in pipe Wireshark writes toolbar messages to
+func openControlIn(name string) (io.ReadCloser, error) {
+	pipe, err := os.OpenFile(name, os.O_RDONLY, os.ModeNamedPipe)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open control-in pipe: %w", err)
+	}
+
+	return pipe, nil
+}
+
+// openControlOut opens the control-out pipe Wireshark reads toolbar messages from
+func openControlOut(name string) (io.WriteCloser, error) {
+	pipe, err := os.OpenFile(name, os.O_WRONLY, os.ModeNamedPipe)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open control-out pipe: %w", err)
+	}
+
+	return pipe, nil
+}
+
+// openControl opens both control pipes and starts the control channel.
+// The control-out pipe is opened first, as Wireshark does not write to control-in before it reads from control-out.
+func openControl(inName, outName string, controls []ToolbarControl, cancel context.CancelCauseFunc) (*ControlChannel, error) {
+	out, err := openControlOut(outName)
+	if err != nil {
+		return nil, err
+	}
+
+	in, err := openControlIn(inName)
+	if err != nil {
+		_ = out.Close()
+		return nil, err
+	}
+
+	return newControlChannel(in, out, controls, cancel), nil
+}
+
+// SetValue sets value of the control, e.g. selects value of the selector
+func (c *ControlChannel) SetValue(control uint8, value string) error {
+	return c.Send(control, ControlSet, []byte(value))
+}
+
+// AddValue adds value to the selector
+func (c *ControlChannel) AddValue(control uint8, val OptValue) error {
+	payload := []byte(val.Value)
+	if val.Display != "" {
+		payload = append(append(payload, 0), val.Display...)
+	}
+
+	return c.Send(control, ControlAdd, payload)
+}
+
+// RemoveValue removes value from the selector
+func (c *ControlChannel) RemoveValue(control uint8, value string) error {
+	return c.Send(control, ControlRemove, []byte(value))
+}
+
+// ClearValues removes all values from the selector
+func (c *ControlChannel) ClearValues(control uint8) error {
+	return c.Send(control, ControlRemove, nil)
+}
+
+// SetBoolean sets state of the checkbox
+func (c *ControlChannel) SetBoolean(control uint8, checked bool) error {
+	var payload byte
+	if checked {
+		payload = 1
+	}
+
+	return c.Send(control, ControlSet, []byte{payload})
+}
+
+// StatusbarMessage shows message in the Wireshark status bar
+func (c *ControlChannel) StatusbarMessage(msg string) error {
+	return c.Send(controlNone, ControlStatusbar, []byte(msg))
+}
+
+// InformationMessage shows information dialog in Wireshark
+func (c *ControlChannel) InformationMessage(msg string) error {
+	return c.Send(controlNone, ControlInformationMessage, []byte(msg))
+}
+
+// WarningMessage shows warning dialog in Wireshark
+func (c *ControlChannel) WarningMessage(msg string) error {
+	return c.Send(controlNone, ControlWarningMessage, []byte(msg))
+}
+
+// ErrorMessage shows error dialog in Wireshark
+func (c *ControlChannel) ErrorMessage(msg string) error {
+	return c.Send(controlNone, ControlErrorMessage, []byte(msg))
+}
+
+// SetEnabled enables or disables the control in the toolbar
+func (c *ControlChannel) SetEnabled(control uint8, enabled bool) error {
+	command := ControlDisable
+	if enabled {
+		command = ControlEnable
+	}
+
+	return c.Send(control, command, nil)
+}
@@ -0,0 +1,422 @@
+package extcap
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestControlChannel(t *testing.T) {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	c := newControlChannel(inR, outW, nil, nil)
+
+	go func() {
+		_, _ = inW.Write([]byte{'T', 0, 0, 5, 1, byte(ControlSet), 'a', 'b', 'c'})
+	}()
+	msg := <-c.Messages()
+	assert.Equal(t, ControlMessage{Control: 1, Command: ControlSet, Payload: []byte("abc")}, msg)
+
+	go func() {
+		_, _ = inW.Write([]byte{'T', 0, 0, 2, 0, byte(ControlInitialized)})
+	}()
+	<-c.Initialized()
+
+	go func() {
+		assert.NoError(t, c.Send(2, ControlStatusbar, []byte("hi")))
+	}()
+	frame := make([]byte, 8)
+	_, err := io.ReadFull(outR, frame)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{'T', 0, 0, 4, 2, byte(ControlStatusbar), 'h', 'i'}, frame)
+
+	go func() {
+		assert.NoError(t, c.AddValue(1, OptValue{Value: "3", Display: "Ch"}))
+	}()
+	frame = make([]byte, 10)
+	_, err = io.ReadFull(outR, frame)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{'T', 0, 0, 6, 1, byte(ControlAdd), '3', 0, 'C', 'h'}, frame)
+
+	go func() {
+		assert.NoError(t, c.Logger(0).Printf("n=%d", 7))
+	}()
+	frame = make([]byte, 10)
+	_, err = io.ReadFull(outR, frame)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{'T', 0, 0, 6, 0, byte(ControlAdd), 'n', '=', '7', '\n'}, frame)
+
+	go func() {
+		assert.NoError(t, c.StatusbarMessage("ok"))
+	}()
+	frame = make([]byte, 8)
+	_, err = io.ReadFull(outR, frame)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{'T', 0, 0, 4, 255, byte(ControlStatusbar), 'o', 'k'}, frame)
+
+	go func() {
+		assert.NoError(t, c.WarningMessage("!"))
+	}()
+	frame = make([]byte, 7)
+	_, err = io.ReadFull(outR, frame)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{'T', 0, 0, 3, 255, byte(ControlWarningMessage), '!'}, frame)
+
+	assert.NoError(t, c.Close())
+	assert.ErrorIs(t, c.Send(2, ControlStatusbar, nil), ErrControlClosed)
+}
+
+func TestControlButtonPress(t *testing.T) {
+	inR, inW := io.Pipe()
+	pressed := make(chan struct{})
+	button := NewControlButton("Reconnect").Number(1).OnPress(func() { close(pressed) })
+	c := newControlChannel(inR, nopWriteCloser{io.Discard}, []ToolbarControl{button}, nil)
+
+	_, _ = inW.Write([]byte{'T', 0, 0, 2, 1, byte(ControlSet)})
+	<-pressed
+
+	_, _ = inW.Write([]byte{'T', 0, 0, 2, 2, byte(ControlSet)})
+	assert.Equal(t, ControlMessage{Control: 2, Command: ControlSet}, <-c.Messages())
+	assert.NoError(t, c.Close())
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+func TestControlMessageFraming(t *testing.T) {
+	testCases := []struct {
+		name  string
+		msg   ControlMessage
+		frame []byte
+	}{
+		{"Empty payload", ControlMessage{Control: 3, Command: ControlEnable}, []byte{'T', 0, 0, 2, 3, 4}},
+		{"Initialized", ControlMessage{Control: 0, Command: ControlInitialized}, []byte{'T', 0, 0, 2, 0, 0}},
+		{"Set value", ControlMessage{Control: 1, Command: ControlSet, Payload: []byte("ch2")}, []byte{'T', 0, 0, 5, 1, 1, 'c', 'h', '2'}},
+		{"Binary payload", ControlMessage{Control: 2, Command: ControlSet, Payload: []byte{0}}, []byte{'T', 0, 0, 3, 2, 1, 0}},
+		{"Error message", ControlMessage{Control: 255, Command: ControlErrorMessage, Payload: []byte("x")}, []byte{'T', 0, 0, 3, 255, 9, 'x'}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := new(bytes.Buffer)
+			assert.NoError(t, WriteControlMessage(w, tc.msg))
+			assert.Equal(t, tc.frame, w.Bytes())
+
+			msg, err := ReadControlMessage(bytes.NewReader(tc.frame))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.msg, msg)
+		})
+	}
+}
+
+func TestControlMessageLength(t *testing.T) {
+	payload := bytes.Repeat([]byte{'a'}, 70000)
+	w := new(bytes.Buffer)
+	assert.NoError(t, WriteControlMessage(w, ControlMessage{Control: 1, Command: ControlAdd, Payload: payload}))
+	assert.Equal(t, []byte{'T', 0x01, 0x11, 0x72}, w.Bytes()[:4])
+
+	msg, err := ReadControlMessage(w)
+	assert.NoError(t, err)
+	assert.Equal(t, payload, msg.Payload)
+
+	err = WriteControlMessage(io.Discard, ControlMessage{Payload: make([]byte, controlMaxLength-1)})
+	assert.ErrorIs(t, err, ErrInvalidControlMessage)
+}
+
+func TestControlMessageInvalid(t *testing.T) {
+	testCases := []struct {
+		name  string
+		frame []byte
+		err   error
+	}{
+		{"Empty", nil, io.EOF},
+		{"Short header", []byte{'T', 0, 0}, io.ErrUnexpectedEOF},
+		{"Sync indicator", []byte{'E', 0, 0, 2, 0, 0}, ErrInvalidControlMessage},
+		{"Length too short", []byte{'T', 0, 0, 1, 0, 0}, ErrInvalidControlMessage},
+		{"Truncated payload", []byte{'T', 0, 0, 5, 0, 1, 'a'}, io.ErrUnexpectedEOF},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ReadControlMessage(bytes.NewReader(tc.frame))
+			assert.ErrorIs(t, err, tc.err)
+		})
+	}
+}
+
+func TestControlMessageSequence(t *testing.T) {
+	w := new(bytes.Buffer)
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, WriteControlMessage(w, ControlMessage{Control: uint8(i), Command: ControlAdd, Payload: []byte{byte('a' + i)}}))
+	}
+
+	for i := 0; i < 3; i++ {
+		msg, err := ReadControlMessage(w)
+		assert.NoError(t, err)
+		assert.Equal(t, uint8(i), msg.Control)
+		assert.Equal(t, []byte{byte('a' + i)}, msg.Payload)
+	}
+
+	_, err := ReadControlMessage(w)
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestControlChannelInitialized(t *testing.T) {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	c := newControlChannel(inR, outW, nil, nil)
+
+	// written only after Wireshark is initialized
+	assert.NoError(t, c.SetEnabled(1, false))
+	assert.NoError(t, c.SetEnabled(2, true))
+
+	go func() {
+		_, _ = inW.Write([]byte{'T', 0, 0, 2, 0, byte(ControlInitialized)})
+	}()
+
+	frame := make([]byte, 12)
+	_, err := io.ReadFull(outR, frame)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{'T', 0, 0, 2, 1, byte(ControlDisable), 'T', 0, 0, 2, 2, byte(ControlEnable)}, frame)
+	<-c.Initialized()
+
+	assert.NoError(t, c.Close())
+}
+
+func TestControlStopButton(t *testing.T) {
+	inR, inW := io.Pipe()
+	ctx, cancel := context.WithCancelCause(context.Background())
+	button := NewControlButton("Stop").StopCapture()
+	c := newControlChannel(inR, nopWriteCloser{io.Discard}, []ToolbarControl{button}, cancel)
+
+	_, _ = inW.Write([]byte{'T', 0, 0, 2, 0, byte(ControlSet)})
+	<-ctx.Done()
+	assert.NoError(t, c.Close())
+}
+
+func TestControlHostClosed(t *testing.T) {
+	inR, inW := io.Pipe()
+	ctx, cancel := context.WithCancelCause(context.Background())
+	c := newControlChannel(inR, nopWriteCloser{io.Discard}, nil, cancel)
+
+	assert.NoError(t, inW.Close())
+	<-ctx.Done()
+	assert.ErrorIs(t, context.Cause(ctx), ErrHostClosed)
+	for range c.Messages() {
+	}
+	assert.ErrorIs(t, c.Err(), ErrHostClosed)
+	assert.NoError(t, c.Close())
+}
+
+func TestControlDispatch(t *testing.T) {
+	inR, inW := io.Pipe()
+	got := make(chan ControlMessage, 1)
+	controls := []ToolbarControl{NewControlButton("Help").Number(1).Role(ButtonRoleHelp)}
+	c := newControlChannel(inR, nopWriteCloser{io.Discard}, controls, nil)
+	c.Handle(7, func(msg ControlMessage) { got <- msg })
+
+	_, _ = inW.Write([]byte{'T', 0, 0, 2, 1, byte(ControlSet)})
+	_, _ = inW.Write([]byte{'T', 0, 0, 3, 7, byte(ControlSet), 'x'})
+	assert.Equal(t, ControlMessage{Control: 7, Command: ControlSet, Payload: []byte("x")}, <-got)
+
+	c.Handle(7, nil)
+	_, _ = inW.Write([]byte{'T', 0, 0, 2, 7, byte(ControlSet)})
+	assert.Equal(t, ControlMessage{Control: 7, Command: ControlSet}, <-c.Messages())
+	assert.NoError(t, c.Close())
+}
+
+func TestControlHandlerSend(t *testing.T) {
+	inR, inW := io.Pipe()
+	out := new(recordingWriter)
+	c := newControlChannel(inR, out, nil, nil)
+	done := make(chan struct{})
+	c.Handle(7, func(msg ControlMessage) {
+		defer close(done)
+		// more than is buffered before Wireshark is initialized
+		for i := 0; i < controlQueueSize+10; i++ {
+			assert.NoError(t, c.Send(7, ControlStatusbar, []byte("x")))
+		}
+	})
+
+	_, _ = inW.Write([]byte{'T', 0, 0, 2, 7, byte(ControlSet)})
+	_, _ = inW.Write([]byte{'T', 0, 0, 2, 0, byte(ControlInitialized)})
+	<-done
+	assert.Len(t, out.messages(), controlQueueSize+10)
+	assert.NoError(t, c.Close())
+}
+
+func TestControlSelectorValues(t *testing.T) {
+	selector := NewControlSelector("Interface").Number(4)
+	assert.ErrorIs(t, selector.Clear(), ErrControlClosed)
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	c := newControlChannel(inR, outW, []ToolbarControl{selector}, nil)
+	_, _ = inW.Write([]byte{'T', 0, 0, 2, 0, byte(ControlInitialized)})
+	<-c.Initialized()
+
+	go func() {
+		assert.NoError(t, selector.AddValue(OptValue{Value: "eth1"}))
+		assert.NoError(t, selector.RemoveValue("eth0"))
+		assert.NoError(t, selector.Clear())
+	}()
+	frame := make([]byte, 26)
+	_, err := io.ReadFull(outR, frame)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{
+		'T', 0, 0, 6, 4, byte(ControlAdd), 'e', 't', 'h', '1',
+		'T', 0, 0, 6, 4, byte(ControlRemove), 'e', 't', 'h', '0',
+		'T', 0, 0, 2, 4, byte(ControlRemove),
+	}, frame)
+	assert.NoError(t, c.Close())
+}
+
+func TestControlRestoreDefaults(t *testing.T) {
+	restored := make(chan struct{})
+	controls := []ToolbarControl{
+		NewControlButton("Defaults").OnRestoreDefaults(func() { close(restored) }),
+		NewControlBoolean("Verbose").Default(true),
+		NewControlSelector("Channel").Values(OptValue{Value: "a"}, OptValue{Value: "b", Default: true}),
+		NewControlString("Note").Default("x"),
+	}
+	assert.NoError(t, numberControls(controls))
+
+	inR, inW := io.Pipe()
+	out := new(safeBuffer)
+	c := newControlChannel(inR, out, controls, nil)
+	_, _ = inW.Write([]byte{'T', 0, 0, 2, 0, byte(ControlInitialized)})
+	_, _ = inW.Write([]byte{'T', 0, 0, 2, 0, byte(ControlSet)})
+	<-restored
+
+	assert.Equal(t, []byte{
+		'T', 0, 0, 3, 1, byte(ControlSet), 1,
+		'T', 0, 0, 3, 2, byte(ControlSet), 'b',
+		'T', 0, 0, 3, 3, byte(ControlSet), 'x',
+	}, out.Bytes())
+	assert.NoError(t, c.Close())
+}
+
+// safeBuffer is a bytes.Buffer which can be written and read from different goroutines
+type safeBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *safeBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *safeBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]byte(nil), b.buf.Bytes()...)
+}
+
+func (b *safeBuffer) Close() error {
+	return nil
+}
+
+func TestControlTester(t *testing.T) {
+	var checked bool
+	verbose := NewControlBoolean("Verbose").OnChange(func(v bool) { checked = v })
+	tester, err := NewControlTester(NewControlButton("Stop").StopCapture(), verbose)
+	assert.NoError(t, err)
+
+	assert.NoError(t, tester.Channel.StatusbarMessage("early"))
+	assert.Empty(t, tester.Sent())
+	assert.NoError(t, tester.Initialize())
+	assert.Equal(t, []ControlMessage{{Control: 255, Command: ControlStatusbar, Payload: []byte("early")}}, tester.Sent())
+
+	assert.NoError(t, tester.Receive(1, ControlSet, []byte{1}))
+	assert.True(t, checked)
+
+	assert.NoError(t, tester.Receive(0, ControlSet, nil))
+	assert.ErrorIs(t, tester.Context.Err(), context.Canceled)
+
+	tester.HostClose()
+	for range tester.Channel.Messages() {
+	}
+	assert.ErrorIs(t, tester.Channel.Err(), ErrHostClosed)
+	assert.NoError(t, tester.Close())
+}
+
+func TestCaptureSessionStats(t *testing.T) {
+	session := &CaptureSession{start: time.Now()}
+	assert.Zero(t, session.Stats().LastWrite)
+
+	session.CountPacket(100)
+	session.CountDropped(2)
+	stats := session.Stats()
+	assert.Equal(t, uint64(1), stats.Packets)
+	assert.Equal(t, uint64(100), stats.Bytes)
+	assert.Equal(t, uint64(2), stats.Dropped)
+	assert.Equal(t, session.start, stats.Start)
+	assert.False(t, stats.LastWrite.Before(stats.Start))
+}
+
+func TestStatsReporter(t *testing.T) {
+	tester, err := NewControlTester()
+	assert.NoError(t, err)
+	assert.NoError(t, tester.Initialize())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	session := &CaptureSession{Context: ctx, Control: tester.Channel}
+	session.CountPacket(100)
+	session.CountPacket(50)
+	session.CountDropped(1)
+
+	done := make(chan struct{})
+	go func() {
+		(&StatsReporter{Interval: 10 * time.Millisecond}).run(session)
+		close(done)
+	}()
+	assert.Eventually(t, func() bool { return len(tester.Sent()) > 0 }, time.Second, time.Millisecond)
+	cancel()
+	<-done
+
+	msg := tester.Sent()[0]
+	assert.Equal(t, ControlStatusbar, msg.Command)
+	assert.Contains(t, string(msg.Payload), "2 packets, 150 bytes, 1 dropped")
+	assert.NoError(t, tester.Close())
+}
+
+func TestControlConcurrentSend(t *testing.T) {
+	tester, err := NewControlTester()
+	assert.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			logger := tester.Channel.Logger(uint8(i))
+			for j := 0; j < 50; j++ {
+				assert.NoError(t, logger.Printf("goroutine %d message %d", i, j))
+			}
+		}(i)
+	}
+
+	assert.NoError(t, tester.Initialize())
+	wg.Wait()
+
+	sent := tester.Sent()
+	assert.Len(t, sent, 400)
+	for _, msg := range sent {
+		assert.True(t, strings.HasPrefix(string(msg.Payload), fmt.Sprintf("goroutine %d message ", msg.Control)))
+	}
+	assert.NoError(t, tester.Close())
+}
@@ -0,0 +1,171 @@
+package extcap
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+)
+
+// ControlTester runs ControlChannel over in-memory pipes, so toolbar interactions can be unit tested without Wireshark.
+// Incoming messages are scripted with Receive, outgoing ones are recorded and returned by Sent.
+type ControlTester struct {
+	// Channel is the control channel under test, pass it to the capture as CaptureSession.Control
+	Channel *ControlChannel
+	// Context is cancelled the same way as CaptureSession.Context, e.g. by the stop button
+	Context context.Context
+
+	in  *scriptedReader
+	out *recordingWriter
+}
+
+// NewControlTester creates control channel for the controls, the controls are numbered as in App.Controls
+func NewControlTester(controls ...ToolbarControl) (*ControlTester, error) {
+	if err := numberControls(controls); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	t := &ControlTester{
+		Context: ctx,
+		in: &scriptedReader{
+			frames: make(chan []byte),
+			acks:   make(chan struct{}),
+			done:   make(chan struct{}),
+		},
+		out: &recordingWriter{},
+	}
+	t.Channel = newControlChannel(t.in, t.out, controls, cancel)
+
+	return t, nil
+}
+
+// Initialize sends the initialized message, as Wireshark does after it has sent initial values of the controls
+func (t *ControlTester) Initialize() error {
+	if err := t.Receive(0, ControlInitialized, nil); err != nil {
+		return err
+	}
+	<-t.Channel.Initialized()
+	return t.Channel.barrier()
+}
+
+// Receive sends message to the channel as if it came from Wireshark.
+// It returns when the message is processed by the handler or queued to Messages.
+func (t *ControlTester) Receive(control uint8, command ControlCommand, payload []byte) error {
+	w := new(bytes.Buffer)
+	if err := WriteControlMessage(w, ControlMessage{Control: control, Command: command, Payload: payload}); err != nil {
+		return err
+	}
+
+	if err := t.in.send(w.Bytes()); err != nil {
+		return err
+	}
+	t.Channel.dispatched()
+	return nil
+}
+
+// Sent returns messages sent to Wireshark so far
+func (t *ControlTester) Sent() []ControlMessage {
+	return t.out.messages()
+}
+
+// HostClose simulates Wireshark closing the control pipe
+func (t *ControlTester) HostClose() {
+	t.in.closeOnce.Do(func() { close(t.in.frames) })
+}
+
+// Close closes the control channel
+func (t *ControlTester) Close() error {
+	return t.Channel.Close()
+}
+
+// scriptedReader delivers frames one by one, send returns once the reader asks for data after the frame,
+// that is when the message of the frame is queued for dispatch
+type scriptedReader struct {
+	frames    chan []byte
+	acks      chan struct{}
+	done      chan struct{}
+	buf       []byte
+	pending   bool
+	closeOnce sync.Once
+	doneOnce  sync.Once
+}
+
+func (r *scriptedReader) send(frame []byte) error {
+	select {
+	case r.frames <- frame:
+	case <-r.done:
+		return ErrControlClosed
+	}
+
+	select {
+	case <-r.acks:
+		return nil
+	case <-r.done:
+		return ErrControlClosed
+	}
+}
+
+func (r *scriptedReader) Read(p []byte) (int, error) {
+	if len(r.buf) == 0 {
+		if r.pending {
+			r.pending = false
+			select {
+			case r.acks <- struct{}{}:
+			case <-r.done:
+				return 0, io.EOF
+			}
+		}
+
+		select {
+		case frame, ok := <-r.frames:
+			if !ok {
+				return 0, io.EOF
+			}
+			r.buf = frame
+			r.pending = true
+		case <-r.done:
+			return 0, io.EOF
+		}
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *scriptedReader) Close() error {
+	r.doneOnce.Do(func() { close(r.done) })
+	return nil
+}
+
+// recordingWriter keeps frames written by the control channel, every Write is a single frame
+type recordingWriter struct {
+	mu     sync.Mutex
+	frames [][]byte
+}
+
+func (w *recordingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.frames = append(w.frames, append([]byte(nil), p...))
+	return len(p), nil
+}
+
+func (w *recordingWriter) Close() error {
+	return nil
+}
+
+func (w *recordingWriter) messages() []ControlMessage {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	msgs := make([]ControlMessage, 0, len(w.frames))
+	for _, frame := range w.frames {
+		if msg, err := ReadControlMessage(bytes.NewReader(frame)); err == nil {
+			msgs = append(msgs, msg)
+		}
+	}
+	return msgs
+}
@@ -0,0 +1,488 @@
+package extcap
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ToolbarControl is a control of the Wireshark interface toolbar, see
+// https://www.wireshark.org/docs/wsdg_html_chunked/ChCaptureExtcap.html#_toolbar_controls
+type ToolbarControl interface {
+	fmt.Stringer
+
+	// setNumber sets number of the control unless it was set explicitly
+	setNumber(int)
+	// getNumber returns number of the control
+	getNumber() int
+	// display returns label of the control, used in error messages
+	display() string
+	// setTranslator sets function to localize human-readable strings
+	setTranslator(func(string) string)
+	// defaultPayload returns payload of the set command restoring default value, false if the control has no value
+	defaultPayload() ([]byte, bool)
+	// attach binds the control to the channel of the running capture
+	attach(channel *ControlChannel)
+	// handle processes message received for the control, it returns false if the message was not handled
+	handle(channel *ControlChannel, msg ControlMessage) bool
+}
+
+// controlNone is the control number used by messages not related to any control, like status bar messages
+const controlNone = 255
+
+// ctl is the common part of all toolbar controls
+type ctl struct {
+	number     int
+	numberSet  bool
+	displayVal string
+	tooltipVal string
+	translate  func(string) string
+	channel    *ControlChannel
+}
+
+func (c *ctl) setNumber(i int) {
+	if !c.numberSet {
+		c.number = i
+	}
+}
+
+func (c *ctl) getNumber() int {
+	return c.number
+}
+
+func (c *ctl) display() string {
+	return c.displayVal
+}
+
+func (c *ctl) setTranslator(translate func(string) string) {
+	c.translate = translate
+}
+
+func (c *ctl) defaultPayload() ([]byte, bool) {
+	return nil, false
+}
+
+func (c *ctl) attach(channel *ControlChannel) {
+	c.channel = channel
+}
+
+// send sends message to the control, ErrControlClosed is returned if capture with control channel is not running
+func (c *ctl) send(command ControlCommand, payload []byte) error {
+	if c.channel == nil {
+		return ErrControlClosed
+	}
+	return c.channel.Send(uint8(c.number), command, payload)
+}
+
+func (c *ctl) handle(*ControlChannel, ControlMessage) bool {
+	return false
+}
+
+func (c *ctl) text(str string) string {
+	if c.translate != nil {
+		str = c.translate(str)
+	}
+	return escapeText(str)
+}
+
+func (c *ctl) string(ctlType string, params [][2]string) string {
+	w := new(strings.Builder)
+	_, _ = fmt.Fprintf(w, "control {number=%d}{type=%s}{display=%s}", c.number, ctlType, c.text(c.displayVal))
+
+	if c.tooltipVal != "" {
+		_, _ = fmt.Fprintf(w, "{tooltip=%s}", c.text(c.tooltipVal))
+	}
+
+	for i := range params {
+		val := params[i][1]
+		if params[i][0] == "placeholder" {
+			val = c.text(val)
+		} else {
+			val = escapeValue(val)
+		}
+		_, _ = fmt.Fprintf(w, "{%s=%s}", params[i][0], val)
+	}
+
+	return w.String()
+}
+
+// numberControls assigns numbers to controls in the order they are defined, keeping explicitly set ones
+func numberControls(controls []ToolbarControl) error {
+	byNumber := make(map[int]string)
+	for i := range controls {
+		controls[i].setNumber(i)
+
+		n := controls[i].getNumber()
+		if n < 0 || n >= controlNone {
+			return fmt.Errorf("%w: control %q has number %d, expected 0-%d", ErrValueOutOfRange, controls[i].display(), n, controlNone-1)
+		}
+		if other, ok := byNumber[n]; ok {
+			return fmt.Errorf("%w: controls %q and %q have number %d", ErrControlNumberCollision, other, controls[i].display(), n)
+		}
+		byNumber[n] = controls[i].display()
+	}
+
+	return nil
+}
+
+// ButtonRole defines what happens when toolbar button is pressed
+type ButtonRole string
+
+const (
+	// ButtonRoleControl button sends a message to the extcap application, handled by OnPress
+	ButtonRoleControl ButtonRole = "control"
+	// ButtonRoleLogger button opens window with messages sent by the extcap application
+	ButtonRoleLogger ButtonRole = "logger"
+	// ButtonRoleHelp button opens help page configured with --extcap-interfaces
+	ButtonRoleHelp ButtonRole = "help"
+	// ButtonRoleRestore button restores default values of all controls
+	ButtonRoleRestore ButtonRole = "restore"
+)
+
+// ControlButton is a button of the interface toolbar
+type ControlButton struct {
+	ctl
+	role    ButtonRole
+	stop    bool
+	onPress func()
+}
+
+// NewControlButton creates new toolbar button
+func NewControlButton(display string) *ControlButton {
+	c := &ControlButton{}
+	c.displayVal = display
+
+	return c
+}
+
+// Tooltip sets tooltip of the button
+func (c *ControlButton) Tooltip(tooltip string) *ControlButton {
+	c.tooltipVal = tooltip
+	return c
+}
+
+// Number sets explicit number of the control, used to address it in control messages
+func (c *ControlButton) Number(number uint8) *ControlButton {
+	c.number = int(number)
+	c.numberSet = true
+	return c
+}
+
+// Role sets role of the button, ButtonRoleControl if not set
+func (c *ControlButton) Role(role ButtonRole) *ControlButton {
+	c.role = role
+	return c
+}
+
+// OnPress sets handler called when user presses the button with ButtonRoleControl.
+// Handlers run one at a time on the control channel dispatch goroutine and may call Send.
+func (c *ControlButton) OnPress(handler func()) *ControlButton {
+	c.onPress = handler
+	return c
+}
+
+// StopCapture makes the button cancel CaptureSession.Context when pressed, so capture can shut down gracefully
+func (c *ControlButton) StopCapture() *ControlButton {
+	c.stop = true
+	return c
+}
+
+// OnRestoreDefaults makes the button restore default values of all toolbar controls when pressed,
+// handler is called after the defaults are sent to Wireshark. It sets the role to ButtonRoleRestore.
+func (c *ControlButton) OnRestoreDefaults(handler func()) *ControlButton {
+	c.role = ButtonRoleRestore
+	c.onPress = handler
+	return c
+}
+
+func (c *ControlButton) handle(channel *ControlChannel, msg ControlMessage) bool {
+	if c.role == ButtonRoleRestore {
+		if msg.Command == ControlSet {
+			channel.restoreDefaults()
+			if c.onPress != nil {
+				c.onPress()
+			}
+		}
+		return true
+	}
+	if c.role != "" && c.role != ButtonRoleControl {
+		// logger and help buttons are handled by Wireshark itself, nothing to do for the capture
+		return true
+	}
+	if (c.onPress == nil && !c.stop) || msg.Command != ControlSet {
+		return false
+	}
+
+	if c.onPress != nil {
+		c.onPress()
+	}
+	if c.stop && channel.cancel != nil {
+		channel.cancel(nil)
+	}
+	return true
+}
+
+// String formats control sentence, e.g.
+// control {number=0}{type=button}{display=Reconnect}{tooltip=Reconnect to the remote host}
+func (c *ControlButton) String() string {
+	var params [][2]string
+	if c.role != "" && c.role != ButtonRoleControl {
+		params = append(params, [2]string{"role", string(c.role)})
+	}
+
+	return c.string("button", params)
+}
+
+// ControlSelector is a drop-down list of the interface toolbar.
+// Values can be changed during capture with AddValue, RemoveValue and SetValue of the ControlChannel.
+type ControlSelector struct {
+	ctl
+	values   []OptValue
+	onChange func(value string)
+}
+
+// NewControlSelector creates new toolbar selector
+func NewControlSelector(display string) *ControlSelector {
+	c := &ControlSelector{}
+	c.displayVal = display
+
+	return c
+}
+
+// Tooltip sets tooltip of the selector
+func (c *ControlSelector) Tooltip(tooltip string) *ControlSelector {
+	c.tooltipVal = tooltip
+	return c
+}
+
+// Number sets explicit number of the control, used to address it in control messages
+func (c *ControlSelector) Number(number uint8) *ControlSelector {
+	c.number = int(number)
+	c.numberSet = true
+	return c
+}
+
+// Values sets initial values of the selector, Parent of the values is ignored
+func (c *ControlSelector) Values(values ...OptValue) *ControlSelector {
+	c.values = values
+	return c
+}
+
+// OnChange sets handler called with the value selected by user.
+// Handlers run one at a time on the control channel dispatch goroutine and may call Send.
+func (c *ControlSelector) OnChange(handler func(value string)) *ControlSelector {
+	c.onChange = handler
+	return c
+}
+
+// AddValue adds value to the selector of the running capture
+func (c *ControlSelector) AddValue(val OptValue) error {
+	if c.channel == nil {
+		return ErrControlClosed
+	}
+	return c.channel.AddValue(uint8(c.number), val)
+}
+
+// RemoveValue removes value from the selector of the running capture
+func (c *ControlSelector) RemoveValue(value string) error {
+	return c.send(ControlRemove, []byte(value))
+}
+
+// Clear removes all values from the selector of the running capture
+func (c *ControlSelector) Clear() error {
+	return c.send(ControlRemove, nil)
+}
+
+func (c *ControlSelector) defaultPayload() ([]byte, bool) {
+	for _, val := range c.values {
+		if val.Default {
+			return []byte(val.Value), true
+		}
+	}
+	return nil, false
+}
+
+func (c *ControlSelector) handle(_ *ControlChannel, msg ControlMessage) bool {
+	if c.onChange == nil || msg.Command != ControlSet {
+		return false
+	}
+
+	c.onChange(string(msg.Payload))
+	return true
+}
+
+// String formats control sentence with the value sentences, e.g.
+// control {number=1}{type=selector}{display=Channel}
+// value {control=1}{value=1}{display=Channel 1}{default=true}
+func (c *ControlSelector) String() string {
+	w := new(strings.Builder)
+	w.WriteString(c.string("selector", nil))
+
+	for _, val := range c.values {
+		_, _ = fmt.Fprintf(w, "\nvalue {control=%d}{value=%s}{display=%s}", c.number, escapeValue(val.Value), c.text(val.Display))
+		if val.Default {
+			w.WriteString("{default=true}")
+		}
+	}
+
+	return w.String()
+}
+
+// ControlBoolean is a checkbox of the interface toolbar
+type ControlBoolean struct {
+	ctl
+	defaultVal bool
+	onChange   func(checked bool)
+}
+
+// NewControlBoolean creates new toolbar checkbox
+func NewControlBoolean(display string) *ControlBoolean {
+	c := &ControlBoolean{}
+	c.displayVal = display
+
+	return c
+}
+
+// Tooltip sets tooltip of the checkbox
+func (c *ControlBoolean) Tooltip(tooltip string) *ControlBoolean {
+	c.tooltipVal = tooltip
+	return c
+}
+
+// Number sets explicit number of the control, used to address it in control messages
+func (c *ControlBoolean) Number(number uint8) *ControlBoolean {
+	c.number = int(number)
+	c.numberSet = true
+	return c
+}
+
+// Default sets initial state of the checkbox
+func (c *ControlBoolean) Default(checked bool) *ControlBoolean {
+	c.defaultVal = checked
+	return c
+}
+
+// OnChange sets handler called with the new state when user toggles the checkbox.
+// Handlers run one at a time on the control channel dispatch goroutine and may call Send.
+func (c *ControlBoolean) OnChange(handler func(checked bool)) *ControlBoolean {
+	c.onChange = handler
+	return c
+}
+
+func (c *ControlBoolean) defaultPayload() ([]byte, bool) {
+	if c.defaultVal {
+		return []byte{1}, true
+	}
+	return []byte{0}, true
+}
+
+func (c *ControlBoolean) handle(_ *ControlChannel, msg ControlMessage) bool {
+	if c.onChange == nil || msg.Command != ControlSet {
+		return false
+	}
+
+	c.onChange(len(msg.Payload) > 0 && msg.Payload[0] != 0)
+	return true
+}
+
+// String formats control sentence, e.g.
+// control {number=2}{type=boolean}{display=Verbose}{default=true}
+func (c *ControlBoolean) String() string {
+	var params [][2]string
+	if c.defaultVal {
+		params = append(params, [2]string{"default", "true"})
+	}
+
+	return c.string("boolean", params)
+}
+
+// ControlString is a text field of the interface toolbar
+type ControlString struct {
+	ctl
+	placeholder  string
+	validation   *regexp.Regexp
+	fullMatch    *regexp.Regexp
+	defaultValue string
+	onChange     func(value string)
+}
+
+// NewControlString creates new toolbar text field
+func NewControlString(display string) *ControlString {
+	c := &ControlString{}
+	c.displayVal = display
+
+	return c
+}
+
+// Tooltip sets tooltip of the text field
+func (c *ControlString) Tooltip(tooltip string) *ControlString {
+	c.tooltipVal = tooltip
+	return c
+}
+
+// Number sets explicit number of the control, used to address it in control messages
+func (c *ControlString) Number(number uint8) *ControlString {
+	c.number = int(number)
+	c.numberSet = true
+	return c
+}
+
+// Placeholder sets text shown in the empty field
+func (c *ControlString) Placeholder(str string) *ControlString {
+	c.placeholder = str
+	return c
+}
+
+// Validation sets regular expression the value must match. Wireshark checks it while user edits the field,
+// values not matching the whole expression are not passed to OnChange handler.
+func (c *ControlString) Validation(str string) *ControlString {
+	c.validation = regexp.MustCompile(str)
+	c.fullMatch = regexp.MustCompile("^(?:" + str + ")$")
+	return c
+}
+
+// Default sets initial value of the text field
+func (c *ControlString) Default(value string) *ControlString {
+	c.defaultValue = value
+	return c
+}
+
+// OnChange sets handler called with the value entered by user.
+// Handlers run one at a time on the control channel dispatch goroutine and may call Send.
+func (c *ControlString) OnChange(handler func(value string)) *ControlString {
+	c.onChange = handler
+	return c
+}
+
+func (c *ControlString) defaultPayload() ([]byte, bool) {
+	return []byte(c.defaultValue), true
+}
+
+func (c *ControlString) handle(_ *ControlChannel, msg ControlMessage) bool {
+	if c.onChange == nil || msg.Command != ControlSet {
+		return false
+	}
+
+	value := string(msg.Payload)
+	if c.fullMatch == nil || c.fullMatch.MatchString(value) {
+		c.onChange(value)
+	}
+	return true
+}
+
+// String formats control sentence, e.g.
+// control {number=3}{type=string}{display=Note}{placeholder=Enter note}{validation=\w+}
+func (c *ControlString) String() string {
+	var params [][2]string
+	if c.placeholder != "" {
+		params = append(params, [2]string{"placeholder", c.placeholder})
+	}
+	if c.validation != nil {
+		params = append(params, [2]string{"validation", c.validation.String()})
+	}
+	if c.defaultValue != "" {
+		params = append(params, [2]string{"default", c.defaultValue})
+	}
+
+	return c.string("string", params)
+}
@@ -0,0 +1,93 @@
+package extcap
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+)
+
+// CustomOption is implemented by third parties to provide extcap arg types the library does not ship.
+// Wrap it with NewConfigCustomOpt to use it together with the other config options.
+type CustomOption interface {
+	// Sentence returns the arg sentence, followed by value sentences if any, of the option with given number, e.g.
+	//   arg {number=5}{call=--logfile}{display=Log file}{type=fileselect}{mustexist=true}
+	Sentence(number int) string
+
+	// Flag returns cli flag parsing the value of the option, the first name of the flag is the name of the option
+	Flag() cli.Flag
+}
+
+// CustomConverter can be implemented by CustomOption to turn value of its cli flag into the value passed to StartCapture
+type CustomConverter interface {
+	Convert(val interface{}) (interface{}, error)
+}
+
+// ConfigCustomOpt implements ConfigOption interface for CustomOption
+type ConfigCustomOpt struct {
+	cfg
+	custom CustomOption
+}
+
+// NewConfigCustomOpt Create new option of custom type
+func NewConfigCustomOpt(custom CustomOption) *ConfigCustomOpt {
+	opt := &ConfigCustomOpt{custom: custom}
+	if names := custom.Flag().Names(); len(names) > 0 {
+		opt.callValue = names[0]
+	}
+
+	return opt
+}
+
+// Required sets if the option is required
+func (c *ConfigCustomOpt) Required(required bool) *ConfigCustomOpt {
+	c.required = required
+	return c
+}
+
+// MustSet makes the option required, it is a short form of Required(true)
+func (c *ConfigCustomOpt) MustSet() *ConfigCustomOpt {
+	return c.Required(true)
+}
+
+// Number sets explicit number of the option, so reordering options does not break saved Wireshark preferences
+func (c *ConfigCustomOpt) Number(n int) *ConfigCustomOpt {
+	c.number = n
+	c.numberSet = true
+	return c
+}
+
+// Requires sets options which must be set when this option is set
+func (c *ConfigCustomOpt) Requires(names ...string) *ConfigCustomOpt {
+	c.requires = append(c.requires, names...)
+	return c
+}
+
+// ConflictsWith sets options which must not be set when this option is set
+func (c *ConfigCustomOpt) ConflictsWith(names ...string) *ConfigCustomOpt {
+	c.conflicts = append(c.conflicts, names...)
+	return c
+}
+
+// Validate adds function checking option value before capture is started
+func (c *ConfigCustomOpt) Validate(f func(interface{}) error) *ConfigCustomOpt {
+	c.validators = append(c.validators, f)
+	return c
+}
+
+// String implements string interface
+func (c *ConfigCustomOpt) String() string {
+	return c.custom.Sentence(c.number)
+}
+
+func (c *ConfigCustomOpt) convert(val interface{}) (interface{}, error) {
+	converter, ok := c.custom.(CustomConverter)
+	if !ok {
+		return val, nil
+	}
+
+	v, err := converter.Convert(val)
+	if err != nil {
+		return nil, fmt.Errorf("option --%s: %w: %w", c.callValue, ErrValueInvalid, err)
+	}
+	return v, nil
+}
@@ -0,0 +1,114 @@
+package extcap
+
+import (
+	"hash/maphash"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultDedupWindow is the window of NewDedupWriter if not set
+const DefaultDedupWindow = 100 * time.Millisecond
+
+// DedupWriter suppresses packets with the same content as a packet written within the window before it,
+// e.g. for sources delivering the same frame via multiple taps. The window is measured with packet timestamps.
+type DedupWriter struct {
+	w      PacketWriter
+	window time.Duration
+
+	mu         sync.Mutex
+	seed       maphash.Seed
+	seen       map[uint64]time.Time
+	queue      []dedupEntry
+	suppressed atomic.Uint64
+}
+
+type dedupEntry struct {
+	hash uint64
+	ts   time.Time
+}
+
+// NewDedupWriter creates DedupWriter writing to w, DefaultDedupWindow is used if window is not positive
+func NewDedupWriter(w PacketWriter, window time.Duration) *DedupWriter {
+	if window <= 0 {
+		window = DefaultDedupWindow
+	}
+	return &DedupWriter{
+		w:      w,
+		window: window,
+		seed:   maphash.MakeSeed(),
+		seen:   make(map[uint64]time.Time),
+	}
+}
+
+// WritePacket writes the packet unless it is a duplicate within the window
+func (dw *DedupWriter) WritePacket(ts time.Time, data []byte, origLen int) error {
+	if dw.duplicate(ts, data) {
+		dw.suppressed.Add(1)
+		return nil
+	}
+	return dw.w.WritePacket(ts, data, origLen)
+}
+
+// WritePackets writes the packets of the batch which are not duplicates as one batch,
+// packets of the batch are compared with each other too
+func (dw *DedupWriter) WritePackets(packets []Packet) error {
+	unique := make([]Packet, 0, len(packets))
+	for _, p := range packets {
+		if dw.duplicate(p.Timestamp, p.Data) {
+			dw.suppressed.Add(1)
+			continue
+		}
+		unique = append(unique, p)
+	}
+	if len(unique) == 0 {
+		return nil
+	}
+	return WritePackets(dw.w, unique)
+}
+
+// Flush flushes the deduplicated writer
+func (dw *DedupWriter) Flush() error {
+	return flushWriter(dw.w)
+}
+
+func (dw *DedupWriter) duplicate(ts time.Time, data []byte) bool {
+	h := maphash.Bytes(dw.seed, data)
+
+	dw.mu.Lock()
+	defer dw.mu.Unlock()
+
+	expired := 0
+	for _, e := range dw.queue {
+		if ts.Sub(e.ts) <= dw.window {
+			break
+		}
+		if dw.seen[e.hash].Equal(e.ts) {
+			delete(dw.seen, e.hash)
+		}
+		expired++
+	}
+	dw.queue = dw.queue[expired:]
+
+	if last, ok := dw.seen[h]; ok && ts.Sub(last) <= dw.window {
+		return true
+	}
+	dw.seen[h] = ts
+	dw.queue = append(dw.queue, dedupEntry{hash: h, ts: ts})
+	return false
+}
+
+// Suppressed returns number of duplicate packets not written
+func (dw *DedupWriter) Suppressed() uint64 {
+	return dw.suppressed.Load()
+}
+
+// ReportDropped passes the drops to the deduplicated writer, suppressed duplicates are not drops
+func (dw *DedupWriter) ReportDropped(id int, n uint64) error {
+	return reportDropped(dw.w, id, n)
+}
+
+// Close closes the deduplicated writer
+func (dw *DedupWriter) Close() error {
+	return closeWriter(dw.w)
+}
@@ -0,0 +1,40 @@
+package extcap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDedupWriter(t *testing.T) {
+	w := new(recordingPacketWriter)
+	dw := NewDedupWriter(w, time.Second)
+	start := time.Unix(1700000000, 0)
+
+	assert.NoError(t, dw.WritePacket(start, []byte{1}, 1))
+	assert.NoError(t, dw.WritePacket(start.Add(time.Millisecond), []byte{2}, 1))
+	assert.NoError(t, dw.WritePacket(start.Add(2*time.Millisecond), []byte{1}, 1))
+	assert.NoError(t, dw.WritePacket(start.Add(2*time.Second), []byte{1}, 1))
+
+	assert.Equal(t, [][]byte{{1}, {2}, {1}}, w.packets)
+	assert.Equal(t, uint64(1), dw.Suppressed())
+	assert.Len(t, dw.seen, 1)
+}
+
+func TestDedupWriterBatch(t *testing.T) {
+	w := new(batchRecordingWriter)
+	dw := NewDedupWriter(w, time.Second)
+	start := time.Unix(1700000000, 0)
+
+	assert.NoError(t, dw.WritePackets([]Packet{
+		{Timestamp: start, Data: []byte{1}},
+		{Timestamp: start, Data: []byte{2}},
+		{Timestamp: start.Add(time.Millisecond), Data: []byte{1}},
+	}))
+	assert.NoError(t, dw.Flush())
+	assert.Equal(t, [][]byte{{1}, {2}}, w.packets)
+	assert.Equal(t, 1, w.batches)
+	assert.Equal(t, 1, w.flushes)
+	assert.Equal(t, uint64(1), dw.Suppressed())
+}
@@ -1,6 +1,9 @@
 package extcap
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
 	// ErrNoInterfaceSpecified is returned when start capture is called without specifying an interface
@@ -9,4 +12,100 @@ var (
 
 	// ErrNoPipeProvided is returned when start capture is called without providing the FIFO pipe to write to
 	ErrNoPipeProvided = errors.New("no FIFO pipe provided")
+
+	// ErrValueOutOfRange is returned when value of config option passed on the command line is outside of allowed range
+	ErrValueOutOfRange = errors.New("value out of range")
+
+	// ErrValueInvalid is returned when value of config option passed on the command line does not match the option validation
+	ErrValueInvalid = errors.New("invalid value")
+
+	// ErrUnknownOption is returned when Wireshark asks to reload an option which is not among the interface configuration options
+	ErrUnknownOption = errors.New("unknown config option")
+
+	// ErrOptionRequired is returned when start capture is called without a required config option
+	ErrOptionRequired = errors.New("required option not set")
+
+	// ErrOptionDependency is returned when an option is set without the options it requires or together with the options it conflicts with
+	ErrOptionDependency = errors.New("option dependency violated")
+
+	// ErrOptionNumberCollision is returned when several config options of an interface have the same number
+	ErrOptionNumberCollision = errors.New("config option number collision")
+
+	// ErrOptionRenumbered is returned by VerifyOptionNumbers when config option number differs from the expected one
+	ErrOptionRenumbered = errors.New("config option renumbered")
+
+	// ErrProfileNotFound is returned when profile selected with --profile does not exist in the profiles file
+	ErrProfileNotFound = errors.New("profile not found")
+
+	// ErrRequiredGroup is returned when none of the options of a required group is set
+	ErrRequiredGroup = errors.New("required option group not set")
+
+	// ErrControlNumberCollision is returned when several toolbar controls have the same number
+	ErrControlNumberCollision = errors.New("toolbar control number collision")
+
+	// ErrUnknownInterface is returned when packet is written for interface not added to the pcapng writer
+	ErrUnknownInterface = errors.New("unknown capture interface")
+
+	// ErrWriterClosed is returned when packet is written to closed writer
+	ErrWriterClosed = errors.New("packet writer closed")
+
+	// ErrInvalidControlMessage is returned when message on the control pipe is malformed
+	ErrInvalidControlMessage = errors.New("invalid control message")
+
+	// ErrHostClosed is the cause of CaptureSession.Context cancellation when Wireshark closes the control pipe
+	ErrHostClosed = errors.New("wireshark closed the control pipe")
+
+	// ErrControlClosed is returned when message is sent over closed control channel
+	ErrControlClosed = errors.New("control channel closed")
+
+	// ErrAutostop is the cause of CaptureSession.Context cancellation when an autostop condition is reached.
+	// It is returned by CaptureSession.Writer after that, App treats it as a normal stop.
+	ErrAutostop = errors.New("autostop condition reached")
+
+	// ErrFilterSyntax is returned when capture filter can not be parsed
+	ErrFilterSyntax = errors.New("capture filter syntax error")
+
+	// ErrFilterUnsupported is returned when capture filter uses a primitive or link type the filter compiler does not support
+	ErrFilterUnsupported = errors.New("capture filter not supported")
+
+	// ErrUnsupportedLinkType is returned when a packet transform does not support the link type of the interface
+	ErrUnsupportedLinkType = errors.New("link type not supported")
+
+	// ErrInvalidUserRecord is returned when packet data is not a sequence of user record TLVs
+	ErrInvalidUserRecord = errors.New("invalid user record")
+
+	// ErrInvalidKey is returned when the anonymization key does not have the required length
+	ErrInvalidKey = errors.New("invalid anonymization key")
+
+	// ErrInvalidCapture is returned when reading pcap or pcapng data which is malformed or in another format
+	ErrInvalidCapture = errors.New("invalid capture file")
+
+	// ErrRetriesExhausted is returned by Retry when the function failed Backoff.MaxAttempts times
+	ErrRetriesExhausted = errors.New("retries exhausted")
+
+	// ErrPipeOpenTimeout is returned when the fifo could not be opened because Wireshark did not open its end in time
+	ErrPipeOpenTimeout = errors.New("timeout opening the fifo")
+
+	// ErrPipeClosed is returned by the packet writers when Wireshark closed the fifo, i.e. the capture was stopped.
+	// It is also the cause of CaptureSession.Context cancellation then, App treats it as a normal stop.
+	ErrPipeClosed = errors.New("wireshark closed the fifo")
 )
+
+// OptionError is returned when value passed on the command line can not be parsed as the type of the config option
+type OptionError struct {
+	// Option is the name of the option
+	Option string
+	// Value is the value passed on the command line
+	Value string
+	// Expected describes the format of the value
+	Expected string
+}
+
+func (e *OptionError) Error() string {
+	return fmt.Sprintf("option --%s: invalid value %q, expected %s", e.Option, e.Value, e.Expected)
+}
+
+// Unwrap returns ErrValueInvalid, so errors.Is(err, ErrValueInvalid) matches all invalid values
+func (e *OptionError) Unwrap() error {
+	return ErrValueInvalid
+}
@@ -0,0 +1,28 @@
+package extcap
+
+import "strings"
+
+// Wireshark reads extcap sentences line by line and takes everything between "{name=" and the
+// first "}" followed by "{", whitespace or end of line as the value of the field.
+// Line breaks, and braces in the wrong place, corrupt the sentence, so every string written
+// into a sentence goes through one of the functions below.
+
+var (
+	// textReplacer makes human-readable strings (display, tooltip, placeholder, ...) safe, braces are shown as parentheses
+	textReplacer = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ", "{", "(", "}", ")")
+
+	// valueReplacer makes machine-readable strings (value, call, default, ...) safe, these are passed back
+	// to the extcap by Wireshark. A sentence has no escaping, so braces which would start a forged field
+	// are replaced with parentheses as in textReplacer.
+	valueReplacer = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ", "{", "(", "}", ")")
+)
+
+// escapeText returns human-readable string safe to be written into a sentence
+func escapeText(str string) string {
+	return textReplacer.Replace(str)
+}
+
+// escapeValue returns machine-readable string safe to be written into a sentence
+func escapeValue(str string) string {
+	return valueReplacer.Replace(str)
+}
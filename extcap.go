@@ -8,6 +8,9 @@ For minimal application should be implemented following functions:
 	GetDLT:
 	StartCapture:
 
+If Wireshark passes --extcap-control-in and --extcap-control-out, the interface toolbar can be
+driven through the ControlChannel of the CaptureSession passed to StartCapture.
+
 Full working example for Talos Linux can be found at https://github.com/lion7/talosdump
 */
 package extcap
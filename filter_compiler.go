@@ -0,0 +1,480 @@
+package extcap
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/bpf"
+)
+
+// filter expression tree
+type (
+	filterAnd   struct{ left, right filterNode }
+	filterOr    struct{ left, right filterNode }
+	filterNot   struct{ node filterNode }
+	filterConst bool
+	// filterTest matches when the loaded value masked with mask, unless it is 0, equals val
+	filterTest struct {
+		loads []bpf.Instruction
+		mask  uint32
+		val   uint32
+	}
+)
+
+type filterNode interface{}
+
+// filterLink describes where the network layer starts for the link type
+type filterLink struct {
+	offset   uint32
+	ethernet bool
+}
+
+func filterLinkOf(dlt DLT) (filterLink, error) {
+	switch dlt.Number {
+	case 1:
+		return filterLink{offset: 14, ethernet: true}, nil
+	case 12, 14, 101, 228, 229:
+		return filterLink{}, nil
+	default:
+		return filterLink{}, fmt.Errorf("%w: link type %d", ErrFilterUnsupported, dlt.Number)
+	}
+}
+
+// compileFilter parses the expression and generates BPF program returning snaplen for matching packets
+func compileFilter(expr string, dlt DLT) ([]bpf.Instruction, error) {
+	accept := bpf.RetConstant{Val: DefaultSnaplen}
+	if strings.TrimSpace(expr) == "" {
+		return []bpf.Instruction{accept}, nil
+	}
+
+	link, err := filterLinkOf(dlt)
+	if err != nil {
+		return nil, err
+	}
+	p := &filterParser{tokens: tokenizeFilter(expr), link: link}
+	root, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok != "" {
+		return nil, fmt.Errorf("%w: unexpected %q", ErrFilterSyntax, tok)
+	}
+
+	var c filterCodegen
+	match, noMatch := c.newLabel(), c.newLabel()
+	c.gen(root, match, noMatch)
+	c.place(match)
+	c.emit(accept)
+	c.place(noMatch)
+	c.emit(bpf.RetConstant{Val: 0})
+	return c.resolve()
+}
+
+// tokenizeFilter splits the expression to words, parentheses and operators
+func tokenizeFilter(expr string) []string {
+	var tokens []string
+	for _, field := range strings.Fields(expr) {
+		for field != "" {
+			switch {
+			case strings.HasPrefix(field, "&&"), strings.HasPrefix(field, "||"):
+				tokens = append(tokens, field[:2])
+				field = field[2:]
+			case field[0] == '(' || field[0] == ')' || field[0] == '!':
+				tokens = append(tokens, field[:1])
+				field = field[1:]
+			default:
+				end := strings.IndexAny(field, "()!&|")
+				if end <= 0 {
+					end = len(field)
+				}
+				tokens = append(tokens, field[:end])
+				field = field[end:]
+			}
+		}
+	}
+	return tokens
+}
+
+type filterParser struct {
+	tokens []string
+	link   filterLink
+}
+
+func (p *filterParser) peek() string {
+	if len(p.tokens) == 0 {
+		return ""
+	}
+	return p.tokens[0]
+}
+
+func (p *filterParser) next() string {
+	tok := p.peek()
+	if tok != "" {
+		p.tokens = p.tokens[1:]
+	}
+	return tok
+}
+
+// parseExpr parses factors joined with and/or, left to right like pcap-filter
+func (p *filterParser) parseExpr() (filterNode, error) {
+	left, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		if op != "and" && op != "&&" && op != "or" && op != "||" {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		if op == "and" || op == "&&" {
+			left = filterAnd{left, right}
+		} else {
+			left = filterOr{left, right}
+		}
+	}
+}
+
+func (p *filterParser) parseFactor() (filterNode, error) {
+	switch tok := p.peek(); tok {
+	case "":
+		return nil, fmt.Errorf("%w: unexpected end of filter", ErrFilterSyntax)
+	case "not", "!":
+		p.next()
+		node, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		return filterNot{node}, nil
+	case "(":
+		p.next()
+		node, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("%w: missing )", ErrFilterSyntax)
+		}
+		return node, nil
+	default:
+		return p.parsePrimitive()
+	}
+}
+
+// parsePrimitive parses [proto] [src|dst] [host|net|port] [value]
+func (p *filterParser) parsePrimitive() (filterNode, error) {
+	var proto, dir, kind string
+	switch p.peek() {
+	case "ip", "ip6", "arp", "tcp", "udp", "icmp", "icmp6":
+		proto = p.next()
+	}
+	switch p.peek() {
+	case "src", "dst":
+		dir = p.next()
+	}
+	switch p.peek() {
+	case "host", "net", "port":
+		kind = p.next()
+	}
+
+	if dir == "" && kind == "" {
+		if proto != "" {
+			return p.protocol(proto)
+		}
+		if tok := p.peek(); tok != "" && isFilterValue(tok) {
+			kind = "host"
+		} else {
+			return nil, fmt.Errorf("%w: unexpected %q", ErrFilterSyntax, p.next())
+		}
+	}
+
+	value := p.next()
+	if value == "" || !isFilterValue(value) {
+		return nil, fmt.Errorf("%w: missing value after %s", ErrFilterSyntax, strings.TrimSpace(proto+" "+dir+" "+kind))
+	}
+	if kind == "" {
+		kind = "host"
+		if strings.Contains(value, "/") {
+			kind = "net"
+		}
+	}
+
+	switch kind {
+	case "port":
+		return p.port(proto, dir, value)
+	case "net":
+		return p.network(proto, dir, value)
+	default:
+		return p.host(proto, dir, value)
+	}
+}
+
+// isFilterValue reports if tok is not a keyword or operator
+func isFilterValue(tok string) bool {
+	switch tok {
+	case "(", ")", "!", "&&", "||", "and", "or", "not", "ip", "ip6", "arp", "tcp", "udp", "icmp", "icmp6",
+		"src", "dst", "host", "net", "port":
+		return false
+	}
+	return true
+}
+
+// ethertype matches the network protocol, raw IP links are matched by the IP version
+func (p *filterParser) ethertype(proto string) filterNode {
+	if p.link.ethernet {
+		val := map[string]uint32{"ip": 0x0800, "ip6": 0x86dd, "arp": 0x0806}[proto]
+		return filterTest{loads: []bpf.Instruction{bpf.LoadAbsolute{Off: 12, Size: 2}}, val: val}
+	}
+	switch proto {
+	case "ip":
+		return filterTest{loads: []bpf.Instruction{bpf.LoadAbsolute{Off: 0, Size: 1}}, mask: 0xf0, val: 0x40}
+	case "ip6":
+		return filterTest{loads: []bpf.Instruction{bpf.LoadAbsolute{Off: 0, Size: 1}}, mask: 0xf0, val: 0x60}
+	default:
+		return filterConst(false)
+	}
+}
+
+// ipProtocol matches transport protocol of IPv4 or IPv6 packet
+func (p *filterParser) ipProtocol(v6 bool, number uint32) filterNode {
+	if v6 {
+		return filterAnd{p.ethertype("ip6"), p.load(p.link.offset+6, 1, number)}
+	}
+	return filterAnd{p.ethertype("ip"), p.load(p.link.offset+9, 1, number)}
+}
+
+func (p *filterParser) protocol(proto string) (filterNode, error) {
+	switch proto {
+	case "tcp":
+		return filterOr{p.ipProtocol(false, 6), p.ipProtocol(true, 6)}, nil
+	case "udp":
+		return filterOr{p.ipProtocol(false, 17), p.ipProtocol(true, 17)}, nil
+	case "icmp":
+		return p.ipProtocol(false, 1), nil
+	case "icmp6":
+		return p.ipProtocol(true, 58), nil
+	default:
+		return p.ethertype(proto), nil
+	}
+}
+
+// load tests size bytes at the absolute offset
+func (p *filterParser) load(off uint32, size int, val uint32) filterTest {
+	return filterTest{loads: []bpf.Instruction{bpf.LoadAbsolute{Off: off, Size: size}}, val: val}
+}
+
+// directions calls match with false for source and true for destination as requested by dir, any of them matches by default
+func directions(dir string, match func(dst bool) filterNode) filterNode {
+	switch dir {
+	case "src":
+		return match(false)
+	case "dst":
+		return match(true)
+	default:
+		return filterOr{match(false), match(true)}
+	}
+}
+
+func (p *filterParser) host(proto, dir, value string) (filterNode, error) {
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return nil, fmt.Errorf("%w: invalid host %q, only IP addresses are supported", ErrFilterSyntax, value)
+	}
+	off := p.link.offset
+
+	if ip4 := ip.To4(); ip4 != nil {
+		if proto != "" && proto != "ip" {
+			return nil, fmt.Errorf("%w: %s host %s", ErrFilterUnsupported, proto, value)
+		}
+		addr := binary.BigEndian.Uint32(ip4)
+		return filterAnd{p.ethertype("ip"), directions(dir, func(dst bool) filterNode {
+			if dst {
+				return p.load(off+16, 4, addr)
+			}
+			return p.load(off+12, 4, addr)
+		})}, nil
+	}
+
+	if proto != "" && proto != "ip6" {
+		return nil, fmt.Errorf("%w: %s host %s", ErrFilterUnsupported, proto, value)
+	}
+	return filterAnd{p.ethertype("ip6"), directions(dir, func(dst bool) filterNode {
+		start := off + 8
+		if dst {
+			start = off + 24
+		}
+		var node filterNode = p.load(start, 4, binary.BigEndian.Uint32(ip[0:]))
+		for i := uint32(1); i < 4; i++ {
+			node = filterAnd{node, p.load(start+4*i, 4, binary.BigEndian.Uint32(ip[4*i:]))}
+		}
+		return node
+	})}, nil
+}
+
+func (p *filterParser) network(proto, dir, value string) (filterNode, error) {
+	if proto != "" && proto != "ip" {
+		return nil, fmt.Errorf("%w: %s net %s", ErrFilterUnsupported, proto, value)
+	}
+	_, ipNet, err := net.ParseCIDR(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid net %q", ErrFilterSyntax, value)
+	}
+	ip4 := ipNet.IP.To4()
+	if ip4 == nil {
+		return nil, fmt.Errorf("%w: IPv6 net %s", ErrFilterUnsupported, value)
+	}
+
+	mask := binary.BigEndian.Uint32(ipNet.Mask)
+	addr := binary.BigEndian.Uint32(ip4)
+	off := p.link.offset
+	return filterAnd{p.ethertype("ip"), directions(dir, func(dst bool) filterNode {
+		test := p.load(off+12, 4, addr)
+		if dst {
+			test = p.load(off+16, 4, addr)
+		}
+		test.mask = mask
+		return test
+	})}, nil
+}
+
+func (p *filterParser) port(proto, dir, value string) (filterNode, error) {
+	number, err := strconv.ParseUint(value, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid port %q", ErrFilterSyntax, value)
+	}
+	protocols := map[string][]uint32{"": {6, 17}, "tcp": {6}, "udp": {17}}[proto]
+	if protocols == nil {
+		return nil, fmt.Errorf("%w: %s port %s", ErrFilterUnsupported, proto, value)
+	}
+	port := uint32(number)
+	off := p.link.offset
+
+	transport := func(v6 bool) filterNode {
+		var node filterNode = p.ipProtocol(v6, protocols[0])
+		if len(protocols) > 1 {
+			node = filterOr{node, p.ipProtocol(v6, protocols[1])}
+		}
+		return node
+	}
+
+	// IPv4 ports follow the header of variable length in the first fragment
+	unfragmented := filterTest{loads: []bpf.Instruction{bpf.LoadAbsolute{Off: off + 6, Size: 2}}, mask: 0x1fff, val: 0}
+	v4 := filterAnd{filterAnd{transport(false), unfragmented}, directions(dir, func(dst bool) filterNode {
+		portOff := off
+		if dst {
+			portOff += 2
+		}
+		return filterTest{loads: []bpf.Instruction{bpf.LoadMemShift{Off: off}, bpf.LoadIndirect{Off: portOff, Size: 2}}, val: port}
+	})}
+	v6 := filterAnd{transport(true), directions(dir, func(dst bool) filterNode {
+		if dst {
+			return p.load(off+42, 2, port)
+		}
+		return p.load(off+40, 2, port)
+	})}
+	return filterOr{v4, v6}, nil
+}
+
+// filterInsn is instruction with symbolic jump targets
+type filterInsn struct {
+	insn bpf.Instruction
+	// cond is the condition of jump to labels jt and jf, ja is unconditional jump to label jt
+	cond   *bpf.JumpIf
+	ja     bool
+	jt, jf int
+}
+
+// filterCodegen generates short-circuit code, every node jumps to the match or the no match label
+type filterCodegen struct {
+	insns  []filterInsn
+	labels []int
+}
+
+func (c *filterCodegen) newLabel() int {
+	c.labels = append(c.labels, -1)
+	return len(c.labels) - 1
+}
+
+func (c *filterCodegen) place(label int) {
+	c.labels[label] = len(c.insns)
+}
+
+func (c *filterCodegen) emit(insn bpf.Instruction) {
+	c.insns = append(c.insns, filterInsn{insn: insn})
+}
+
+func (c *filterCodegen) gen(node filterNode, match, noMatch int) {
+	switch n := node.(type) {
+	case filterAnd:
+		next := c.newLabel()
+		c.gen(n.left, next, noMatch)
+		c.place(next)
+		c.gen(n.right, match, noMatch)
+	case filterOr:
+		next := c.newLabel()
+		c.gen(n.left, match, next)
+		c.place(next)
+		c.gen(n.right, match, noMatch)
+	case filterNot:
+		c.gen(n.node, noMatch, match)
+	case filterConst:
+		target := noMatch
+		if n {
+			target = match
+		}
+		c.insns = append(c.insns, filterInsn{ja: true, jt: target})
+	case filterTest:
+		for _, load := range n.loads {
+			c.emit(load)
+		}
+		if n.mask != 0 {
+			c.emit(bpf.ALUOpConstant{Op: bpf.ALUOpAnd, Val: n.mask})
+		}
+		c.insns = append(c.insns, filterInsn{cond: &bpf.JumpIf{Cond: bpf.JumpEqual, Val: n.val}, jt: match, jf: noMatch})
+	}
+}
+
+// resolve replaces labels with relative jumps
+func (c *filterCodegen) resolve() ([]bpf.Instruction, error) {
+	program := make([]bpf.Instruction, len(c.insns))
+	for i, in := range c.insns {
+		skip := func(label int) (uint8, error) {
+			n := c.labels[label] - i - 1
+			if n > 255 {
+				return 0, fmt.Errorf("%w: filter too long", ErrFilterUnsupported)
+			}
+			return uint8(n), nil
+		}
+
+		switch {
+		case in.ja:
+			n, err := skip(in.jt)
+			if err != nil {
+				return nil, err
+			}
+			program[i] = bpf.Jump{Skip: uint32(n)}
+		case in.cond != nil:
+			jt, err := skip(in.jt)
+			if err != nil {
+				return nil, err
+			}
+			jf, err := skip(in.jf)
+			if err != nil {
+				return nil, err
+			}
+			cond := *in.cond
+			cond.SkipTrue, cond.SkipFalse = jt, jf
+			program[i] = cond
+		default:
+			program[i] = in.insn
+		}
+	}
+	return program, nil
+}
@@ -3,8 +3,11 @@ module github.com/lion7/extcap
 go 1.21.3
 
 require (
+	github.com/google/gopacket v1.1.19
 	github.com/stretchr/testify v1.8.4
 	github.com/urfave/cli/v2 v2.25.7
+	golang.org/x/net v0.19.0
+	golang.org/x/sys v0.15.0
 )
 
 require (
@@ -0,0 +1,88 @@
+/*
+Package gopacketadapter connects gopacket based capture code with extcap packet writers.
+
+Writer lets code written for pcapgo writers write to CaptureSession.Writer, e.g.
+
+	w := gopacketadapter.NewWriter(session.Writer)
+	for packet := range source.Packets() {
+		err := w.WritePacket(packet.Metadata().CaptureInfo, packet.Data())
+	}
+
+FromWriter goes the other way, it turns pcapgo.Writer or pcapgo.NgWriter into extcap.PacketWriter.
+*/
+package gopacketadapter
+
+import (
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/lion7/extcap"
+)
+
+// WriterInterface is the write method of pcapgo.Writer and pcapgo.NgWriter
+type WriterInterface interface {
+	WritePacket(ci gopacket.CaptureInfo, data []byte) error
+}
+
+// interfaceWriter is implemented by extcap.PcapngWriter
+type interfaceWriter interface {
+	WriteInterfacePacket(id int, ts time.Time, data []byte, origLen int) error
+}
+
+// Writer implements WriterInterface on top of extcap.PacketWriter
+type Writer struct {
+	w extcap.PacketWriter
+}
+
+// NewWriter creates Writer writing to w
+func NewWriter(w extcap.PacketWriter) *Writer {
+	return &Writer{w: w}
+}
+
+// WritePacket writes the packet, InterfaceIndex of ci selects the interface if w is extcap.PcapngWriter
+func (w *Writer) WritePacket(ci gopacket.CaptureInfo, data []byte) error {
+	if ci.InterfaceIndex != 0 {
+		if iw, ok := w.w.(interfaceWriter); ok {
+			return iw.WriteInterfacePacket(ci.InterfaceIndex, ci.Timestamp, data, ci.Length)
+		}
+	}
+	return w.w.WritePacket(ci.Timestamp, data, ci.Length)
+}
+
+// PacketWriter implements extcap.PacketWriter on top of WriterInterface
+type PacketWriter struct {
+	w WriterInterface
+}
+
+// FromWriter creates PacketWriter writing to w
+func FromWriter(w WriterInterface) *PacketWriter {
+	return &PacketWriter{w: w}
+}
+
+// WritePacket writes the packet, origLen shorter than data is corrected
+func (pw *PacketWriter) WritePacket(ts time.Time, data []byte, origLen int) error {
+	if origLen < len(data) {
+		origLen = len(data)
+	}
+	return pw.w.WritePacket(gopacket.CaptureInfo{
+		Timestamp:     ts,
+		CaptureLength: len(data),
+		Length:        origLen,
+	}, data)
+}
+
+// Flush flushes w if it buffers data, like pcapgo.NgWriter
+func (pw *PacketWriter) Flush() error {
+	if f, ok := pw.w.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// Source adapts gopacket.PacketDataSource, e.g. pcap.Handle or pcapgo.Reader, to extcap.PacketSource for extcap.Pump
+func Source(src gopacket.PacketDataSource) extcap.PacketSource {
+	return extcap.PacketSourceFunc(func() (time.Time, []byte, int, error) {
+		data, ci, err := src.ReadPacketData()
+		return ci.Timestamp, data, ci.Length, err
+	})
+}
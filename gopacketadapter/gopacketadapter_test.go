@@ -0,0 +1,38 @@
+package gopacketadapter
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+	"github.com/lion7/extcap"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriter(t *testing.T) {
+	ts := time.Unix(1700000000, 123456000)
+	data := []byte{1, 2, 3}
+
+	expected := new(bytes.Buffer)
+	pw, err := extcap.NewPcapWriter(expected, extcap.DLT{Number: 1})
+	assert.NoError(t, err)
+	assert.NoError(t, pw.WritePacket(ts, data, 60))
+
+	// pcapgo writes the same file header and records
+	buf := new(bytes.Buffer)
+	pcap := pcapgo.NewWriter(buf)
+	assert.NoError(t, pcap.WriteFileHeader(extcap.DefaultSnaplen, layers.LinkTypeEthernet))
+	adapted := FromWriter(pcap)
+	assert.NoError(t, adapted.WritePacket(ts, data, 60))
+	assert.Equal(t, expected.Bytes(), buf.Bytes())
+
+	buf.Reset()
+	pw, err = extcap.NewPcapWriter(buf, extcap.DLT{Number: 1})
+	assert.NoError(t, err)
+	w := NewWriter(pw)
+	assert.NoError(t, w.WritePacket(gopacket.CaptureInfo{Timestamp: ts, CaptureLength: 3, Length: 60}, data))
+	assert.Equal(t, expected.Bytes(), buf.Bytes())
+}
@@ -0,0 +1,57 @@
+package extcap
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Logger sends messages to the log window of the toolbar button with ButtonRoleLogger.
+// It implements io.Writer, so it can be used as output of the log package.
+type Logger struct {
+	channel *ControlChannel
+	control uint8
+}
+
+// Logger returns Logger writing to the log window of the control
+func (c *ControlChannel) Logger(control uint8) *Logger {
+	return &Logger{channel: c, control: control}
+}
+
+// Printf appends formatted message to the log, new line is added if missing
+func (l *Logger) Printf(format string, args ...interface{}) error {
+	return l.add(fmt.Sprintf(format, args...))
+}
+
+// Println appends message to the log, operands are formatted as by fmt.Sprintln
+func (l *Logger) Println(args ...interface{}) error {
+	return l.add(fmt.Sprintln(args...))
+}
+
+// Replace clears the log and writes formatted message to it
+func (l *Logger) Replace(format string, args ...interface{}) error {
+	return l.channel.Send(l.control, ControlSet, []byte(withNewline(fmt.Sprintf(format, args...))))
+}
+
+// Clear removes all messages from the log
+func (l *Logger) Clear() error {
+	return l.channel.Send(l.control, ControlSet, nil)
+}
+
+// Write appends p to the log as is
+func (l *Logger) Write(p []byte) (int, error) {
+	if err := l.channel.Send(l.control, ControlAdd, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (l *Logger) add(msg string) error {
+	return l.channel.Send(l.control, ControlAdd, []byte(withNewline(msg)))
+}
+
+func withNewline(msg string) string {
+	if !strings.HasSuffix(msg, "\n") {
+		msg += "\n"
+	}
+	return msg
+}
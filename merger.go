@@ -0,0 +1,196 @@
+package extcap
+
+import (
+	"container/heap"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultMergeWindow is the reorder window of NewMerger if not set
+const DefaultMergeWindow = 500 * time.Millisecond
+
+// Merger merges packets of several concurrent sources into one pcapng stream, every source has its own interface.
+// Packets are ordered by timestamp within the reorder window: a packet is written once a packet newer by the window
+// arrived from any source or after it waited for the window. Packets arriving later than that are written
+// immediately and counted as late.
+type Merger struct {
+	w      *PcapngWriter
+	window time.Duration
+
+	mu      sync.Mutex
+	queue   mergeQueue
+	newest  time.Time
+	written time.Time
+	seq     uint64
+	err     error
+	closed  bool
+	late    atomic.Uint64
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewMerger creates Merger writing to w, DefaultMergeWindow is used if window is not positive.
+// Merger owns w, it is closed with Merger.Close.
+func NewMerger(w *PcapngWriter, window time.Duration) *Merger {
+	if window <= 0 {
+		window = DefaultMergeWindow
+	}
+	m := &Merger{w: w, window: window, stop: make(chan struct{}), done: make(chan struct{})}
+	go m.run()
+	return m
+}
+
+// AddSource adds interface to the pcapng stream and returns writer for the source capturing on it
+func (m *Merger) AddSource(dlt DLT, info InterfaceInfo) (*MergerSource, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id, err := m.w.AddInterfaceInfo(dlt, info)
+	if err != nil {
+		return nil, err
+	}
+	return &MergerSource{m: m, id: id}, nil
+}
+
+// Source returns writer for the source capturing on interface already added to the pcapng writer, e.g. 0
+func (m *Merger) Source(id int) *MergerSource {
+	return &MergerSource{m: m, id: id}
+}
+
+// Late returns number of packets which arrived after newer packets were already written
+func (m *Merger) Late() uint64 {
+	return m.late.Load()
+}
+
+func (m *Merger) add(id int, ts time.Time, data []byte, origLen int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return ErrWriterClosed
+	}
+	if m.err != nil {
+		return m.err
+	}
+
+	if ts.Before(m.written) {
+		m.late.Add(1)
+		m.err = m.w.WriteInterfacePacket(id, ts, data, origLen)
+		return m.err
+	}
+
+	m.seq++
+	heap.Push(&m.queue, &mergedPacket{
+		id:      id,
+		ts:      ts,
+		data:    append([]byte(nil), data...),
+		origLen: origLen,
+		arrived: time.Now(),
+		seq:     m.seq,
+	})
+	if ts.After(m.newest) {
+		m.newest = ts
+	}
+	return m.releaseLocked(false)
+}
+
+// releaseLocked writes packets older than the newest by the window or waiting longer than the window, all if force
+func (m *Merger) releaseLocked(force bool) error {
+	now := time.Now()
+	for m.err == nil && m.queue.Len() > 0 {
+		p := m.queue[0]
+		if !force && p.ts.After(m.newest.Add(-m.window)) && now.Sub(p.arrived) < m.window {
+			break
+		}
+		heap.Pop(&m.queue)
+		m.written = p.ts
+		m.err = m.w.WriteInterfacePacket(p.id, p.ts, p.data, p.origLen)
+	}
+	return m.err
+}
+
+// run releases packets of sources which went quiet
+func (m *Merger) run() {
+	defer close(m.done)
+	ticker := time.NewTicker(m.window / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+		}
+		m.mu.Lock()
+		_ = m.releaseLocked(false)
+		m.mu.Unlock()
+	}
+}
+
+// Close writes the queued packets and closes the pcapng writer, the sources can not write after it
+func (m *Merger) Close() error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil
+	}
+	m.closed = true
+	err := m.releaseLocked(true)
+	m.mu.Unlock()
+
+	close(m.stop)
+	<-m.done
+	if closeErr := m.w.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// MergerSource writes packets of one source to Merger, it is safe for concurrent use
+type MergerSource struct {
+	m  *Merger
+	id int
+}
+
+// ID returns the pcapng interface ID of the source
+func (s *MergerSource) ID() int {
+	return s.id
+}
+
+// WritePacket queues the packet for writing, the data is copied
+func (s *MergerSource) WritePacket(ts time.Time, data []byte, origLen int) error {
+	return s.m.add(s.id, ts, data, origLen)
+}
+
+// ReportDropped adds n drops to the interface of the source, the id is ignored
+func (s *MergerSource) ReportDropped(_ int, n uint64) error {
+	return s.m.w.ReportDropped(s.id, n)
+}
+
+type mergedPacket struct {
+	id      int
+	ts      time.Time
+	data    []byte
+	origLen int
+	arrived time.Time
+	seq     uint64
+}
+
+// mergeQueue is a heap of packets ordered by timestamp and arrival
+type mergeQueue []*mergedPacket
+
+func (q mergeQueue) Len() int { return len(q) }
+func (q mergeQueue) Less(i, j int) bool {
+	if !q[i].ts.Equal(q[j].ts) {
+		return q[i].ts.Before(q[j].ts)
+	}
+	return q[i].seq < q[j].seq
+}
+func (q mergeQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *mergeQueue) Push(x any)   { *q = append(*q, x.(*mergedPacket)) }
+func (q *mergeQueue) Pop() any {
+	old := *q
+	p := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	return p
+}
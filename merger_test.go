@@ -0,0 +1,77 @@
+package extcap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// enhancedPackets returns interface IDs and first data byte of the Enhanced Packet Blocks in the pcapng stream
+func enhancedPackets(t *testing.T, b []byte) (ids []uint32, first []byte) {
+	for len(b) >= 12 {
+		blockType, length := binary.LittleEndian.Uint32(b), binary.LittleEndian.Uint32(b[4:])
+		require.GreaterOrEqual(t, len(b), int(length))
+		if blockType == 6 {
+			ids = append(ids, binary.LittleEndian.Uint32(b[8:]))
+			first = append(first, b[28])
+		}
+		b = b[length:]
+	}
+	return ids, first
+}
+
+func TestMerger(t *testing.T) {
+	buf := new(bytes.Buffer)
+	pw, err := NewPcapngWriter(buf, DLT{Number: 1})
+	require.NoError(t, err)
+	m := NewMerger(pw, time.Hour)
+
+	eth, err := m.AddSource(DLT{Number: 1}, InterfaceInfo{Name: "eth1"})
+	require.NoError(t, err)
+	wlan, err := m.AddSource(DLT{Number: 105}, InterfaceInfo{Name: "wlan0"})
+	require.NoError(t, err)
+
+	start := time.Unix(1700000000, 0)
+	var wg sync.WaitGroup
+	for _, src := range []*MergerSource{eth, wlan} {
+		wg.Add(1)
+		go func(src *MergerSource) {
+			defer wg.Done()
+			for i := 3; i >= 0; i-- {
+				n := byte(i*2 + src.ID())
+				assert.NoError(t, src.WritePacket(start.Add(time.Duration(n)*time.Second), []byte{n}, 1))
+			}
+		}(src)
+	}
+	wg.Wait()
+	require.NoError(t, m.Close())
+
+	ids, first := enhancedPackets(t, buf.Bytes())
+	assert.Equal(t, []byte{1, 2, 3, 4, 5, 6, 7, 8}, first)
+	assert.Equal(t, []uint32{1, 2, 1, 2, 1, 2, 1, 2}, ids)
+	assert.Zero(t, m.Late())
+	assert.ErrorIs(t, eth.WritePacket(start, []byte{1}, 1), ErrWriterClosed)
+}
+
+func TestMergerWindow(t *testing.T) {
+	buf := new(bytes.Buffer)
+	pw, err := NewPcapngWriter(buf, DLT{Number: 1})
+	require.NoError(t, err)
+	m := NewMerger(pw, time.Second)
+	src := m.Source(0)
+
+	start := time.Unix(1700000000, 0)
+	require.NoError(t, src.WritePacket(start.Add(time.Second), []byte{2}, 1))
+	require.NoError(t, src.WritePacket(start.Add(3*time.Second), []byte{3}, 1))
+	require.NoError(t, src.WritePacket(start, []byte{1}, 1))
+	require.NoError(t, m.Close())
+
+	_, first := enhancedPackets(t, buf.Bytes())
+	assert.Equal(t, []byte{2, 1, 3}, first)
+	assert.Equal(t, uint64(1), m.Late())
+}
@@ -0,0 +1,132 @@
+package extcap
+
+import (
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+// MeteredWriter counts packets written to the underlying writer and measures write latency,
+// so throughput of the capture and performance regressions of the writers can be observed
+type MeteredWriter struct {
+	w       PacketWriter
+	packets atomic.Uint64
+	bytes   atomic.Uint64
+	errors  atomic.Uint64
+	// latency histogram, bucket i counts writes which took less than 2^i ns
+	latency [64]atomic.Uint64
+	max     atomic.Int64
+}
+
+// WriterMetrics are the counters of MeteredWriter, percentiles are upper bounds with power of two precision
+type WriterMetrics struct {
+	Packets uint64
+	Bytes   uint64
+	Errors  uint64
+	P50     time.Duration
+	P90     time.Duration
+	P99     time.Duration
+	Max     time.Duration
+}
+
+// NewMeteredWriter creates MeteredWriter writing to w
+func NewMeteredWriter(w PacketWriter) *MeteredWriter {
+	return &MeteredWriter{w: w}
+}
+
+// WritePacket writes the packet and counts it
+func (mw *MeteredWriter) WritePacket(ts time.Time, data []byte, origLen int) error {
+	start := time.Now()
+	err := mw.w.WritePacket(ts, data, origLen)
+	mw.observe(time.Since(start))
+	if err != nil {
+		mw.errors.Add(1)
+		return err
+	}
+
+	mw.packets.Add(1)
+	mw.bytes.Add(uint64(len(data)))
+	return nil
+}
+
+// WritePackets writes the batch and counts its packets, the latency of the batch is measured as one write
+func (mw *MeteredWriter) WritePackets(packets []Packet) error {
+	start := time.Now()
+	err := WritePackets(mw.w, packets)
+	mw.observe(time.Since(start))
+	if err != nil {
+		mw.errors.Add(1)
+		return err
+	}
+
+	var n int
+	for _, p := range packets {
+		n += len(p.Data)
+	}
+	mw.packets.Add(uint64(len(packets)))
+	mw.bytes.Add(uint64(n))
+	return nil
+}
+
+// Flush flushes the metered writer, it is not measured
+func (mw *MeteredWriter) Flush() error {
+	return flushWriter(mw.w)
+}
+
+// observe adds write latency to the histogram
+func (mw *MeteredWriter) observe(elapsed time.Duration) {
+	mw.latency[bits.Len64(uint64(elapsed))%64].Add(1)
+	for {
+		max := mw.max.Load()
+		if int64(elapsed) <= max || mw.max.CompareAndSwap(max, int64(elapsed)) {
+			break
+		}
+	}
+}
+
+// Metrics returns the current counters
+func (mw *MeteredWriter) Metrics() WriterMetrics {
+	var counts [64]uint64
+	var total uint64
+	for i := range mw.latency {
+		counts[i] = mw.latency[i].Load()
+		total += counts[i]
+	}
+
+	return WriterMetrics{
+		Packets: mw.packets.Load(),
+		Bytes:   mw.bytes.Load(),
+		Errors:  mw.errors.Load(),
+		P50:     percentile(counts[:], total, 0.50),
+		P90:     percentile(counts[:], total, 0.90),
+		P99:     percentile(counts[:], total, 0.99),
+		Max:     time.Duration(mw.max.Load()),
+	}
+}
+
+// ReportDropped passes the drops to the metered writer, they are not part of WriterMetrics
+func (mw *MeteredWriter) ReportDropped(id int, n uint64) error {
+	return reportDropped(mw.w, id, n)
+}
+
+// Close closes the metered writer
+func (mw *MeteredWriter) Close() error {
+	return closeWriter(mw.w)
+}
+
+// percentile returns upper bound of the histogram bucket containing the quantile q
+func percentile(counts []uint64, total uint64, q float64) time.Duration {
+	if total == 0 {
+		return 0
+	}
+
+	rank := uint64(q*float64(total-1)) + 1
+	var seen uint64
+	for i, c := range counts {
+		seen += c
+		if seen >= rank {
+			return time.Duration(1) << i
+		}
+	}
+	return time.Duration(1) << (len(counts) - 1)
+}
@@ -0,0 +1,44 @@
+package extcap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMeteredWriter(t *testing.T) {
+	mw := NewMeteredWriter(new(recordingPacketWriter))
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, mw.WritePacket(time.Now(), make([]byte, 100), 100))
+	}
+
+	m := mw.Metrics()
+	assert.Equal(t, uint64(10), m.Packets)
+	assert.Equal(t, uint64(1000), m.Bytes)
+	assert.Zero(t, m.Errors)
+	assert.LessOrEqual(t, m.P50, m.P99)
+	assert.NotZero(t, m.P99)
+}
+
+func TestMeteredWriterBatch(t *testing.T) {
+	w := new(batchRecordingWriter)
+	mw := NewMeteredWriter(w)
+
+	assert.NoError(t, mw.WritePackets([]Packet{{Data: make([]byte, 100)}, {Data: make([]byte, 50)}}))
+	assert.NoError(t, mw.Flush())
+	assert.Equal(t, 1, w.batches)
+	assert.Equal(t, 1, w.flushes)
+	m := mw.Metrics()
+	assert.Equal(t, uint64(2), m.Packets)
+	assert.Equal(t, uint64(150), m.Bytes)
+}
+
+func TestPercentile(t *testing.T) {
+	counts := make([]uint64, 64)
+	counts[3], counts[10] = 90, 10
+	assert.Equal(t, 8*time.Nanosecond, percentile(counts, 100, 0.5))
+	assert.Equal(t, 8*time.Nanosecond, percentile(counts, 100, 0.9))
+	assert.Equal(t, 1024*time.Nanosecond, percentile(counts, 100, 0.99))
+	assert.Zero(t, percentile(make([]uint64, 64), 0, 0.5))
+}
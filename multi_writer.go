@@ -0,0 +1,104 @@
+package extcap
+
+import (
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+// MultiWriter duplicates packets to all its writers, like io.MultiWriter
+type MultiWriter struct {
+	writers []PacketWriter
+}
+
+// NewMultiWriter creates MultiWriter writing packets to writers in the order they are listed
+func NewMultiWriter(writers ...PacketWriter) *MultiWriter {
+	return &MultiWriter{writers: append([]PacketWriter(nil), writers...)}
+}
+
+// WritePacket writes the packet to every writer, it stops at the first error and returns it
+func (mw *MultiWriter) WritePacket(ts time.Time, data []byte, origLen int) error {
+	for _, w := range mw.writers {
+		if err := w.WritePacket(ts, data, origLen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close closes all writers which are io.Closer and returns their errors joined
+func (mw *MultiWriter) Close() error {
+	var errs []error
+	for _, w := range mw.writers {
+		errs = append(errs, closeWriter(w))
+	}
+	return errors.Join(errs...)
+}
+
+// ReportDropped reports the drops to every writer accounting them, like PcapngWriter
+func (mw *MultiWriter) ReportDropped(id int, n uint64) error {
+	var errs []error
+	for _, w := range mw.writers {
+		errs = append(errs, reportDropped(w, id, n))
+	}
+	return errors.Join(errs...)
+}
+
+// TeeFile wraps format, so packets written to the fifo are also archived in the pcapng file at path.
+// The file is created when the capture starts and closed with the writer.
+func TeeFile(format PacketWriterFunc, path string) PacketWriterFunc {
+	return func(w io.Writer, dlt DLT, opts ...WriterOption) (PacketWriter, error) {
+		fifo, err := format(w, dlt, opts...)
+		if err != nil {
+			return nil, err
+		}
+
+		f, err := os.Create(path)
+		if err != nil {
+			_ = closeWriter(fifo)
+			return nil, err
+		}
+		archive, err := NewPcapngWriter(f, dlt, opts...)
+		if err != nil {
+			_ = f.Close()
+			_ = closeWriter(fifo)
+			return nil, err
+		}
+
+		return NewMultiWriter(fifo, fileWriter{PcapngWriter: archive, f: f}), nil
+	}
+}
+
+// fileWriter closes the file after the writer
+type fileWriter struct {
+	*PcapngWriter
+	f *os.File
+}
+
+func (w fileWriter) Close() error {
+	err := w.PcapngWriter.Close()
+	if cerr := w.f.Close(); !errors.Is(cerr, os.ErrClosed) {
+		err = errors.Join(err, cerr)
+	}
+	return err
+}
+
+// TeeRotating wraps format, so packets written to the fifo are also archived in the ring buffer of files
+// configured by opts, see RotatingWriter
+func TeeRotating(format PacketWriterFunc, opts RotationOptions) PacketWriterFunc {
+	return func(w io.Writer, dlt DLT, wopts ...WriterOption) (PacketWriter, error) {
+		fifo, err := format(w, dlt, wopts...)
+		if err != nil {
+			return nil, err
+		}
+
+		archive, err := NewRotatingWriter(dlt, opts, wopts...)
+		if err != nil {
+			_ = closeWriter(fifo)
+			return nil, err
+		}
+
+		return NewMultiWriter(fifo, archive), nil
+	}
+}
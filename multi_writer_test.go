@@ -0,0 +1,57 @@
+package extcap
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTeeFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.pcapng")
+	fifo := new(bytes.Buffer)
+
+	pw, err := TeeFile(PcapngFormat, path)(fifo, DLT{Number: 1})
+	assert.NoError(t, err)
+	assert.NoError(t, pw.WritePacket(time.Unix(1700000000, 0), []byte{1, 2, 3}, 3))
+	assert.NoError(t, closeWriter(pw))
+	assert.NoError(t, closeWriter(pw))
+
+	archive, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	// the final statistics have different end time, so only the packets are compared
+	assert.Equal(t, fifo.Bytes()[:28+20+36], archive[:28+20+36])
+}
+
+// closeRecordingWriter is recordingPacketWriter recording Close
+type closeRecordingWriter struct {
+	recordingPacketWriter
+	closed bool
+}
+
+func (w *closeRecordingWriter) Close() error {
+	w.closed = true
+	return nil
+}
+
+func TestTeeFileCreateError(t *testing.T) {
+	fifo := new(closeRecordingWriter)
+	format := func(io.Writer, DLT, ...WriterOption) (PacketWriter, error) { return fifo, nil }
+
+	_, err := TeeFile(format, filepath.Join(t.TempDir(), "missing", "archive.pcapng"))(new(bytes.Buffer), DLT{Number: 1})
+	assert.Error(t, err)
+	assert.True(t, fifo.closed)
+}
+
+func TestTeeRotatingError(t *testing.T) {
+	fifo := new(closeRecordingWriter)
+	format := func(io.Writer, DLT, ...WriterOption) (PacketWriter, error) { return fifo, nil }
+
+	_, err := TeeRotating(format, RotationOptions{})(new(bytes.Buffer), DLT{Number: 1})
+	assert.ErrorIs(t, err, ErrValueInvalid)
+	assert.True(t, fifo.closed)
+}
@@ -0,0 +1,159 @@
+package extcap
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Options holds values of configuration options passed to the capture.
+// Keys are option names as given to the option constructor (without leading dashes).
+type Options map[string]interface{}
+
+// String returns value of STRING option or empty string if option is not set
+func (o Options) String(name string) string {
+	val, _ := o[name].(string)
+	return val
+}
+
+// Int returns value of INTEGER option or 0 if option is not set
+func (o Options) Int(name string) int {
+	val, _ := o[name].(int)
+	return val
+}
+
+// Uint returns value of UNSIGNED option or 0 if option is not set
+func (o Options) Uint(name string) uint {
+	val, _ := o[name].(uint)
+	return val
+}
+
+// Int64 returns value of LONG option or 0 if option is not set
+func (o Options) Int64(name string) int64 {
+	val, _ := o[name].(int64)
+	return val
+}
+
+// Float64 returns value of DOUBLE option or 0 if option is not set
+func (o Options) Float64(name string) float64 {
+	val, _ := o[name].(float64)
+	return val
+}
+
+// Bool returns value of BOOL option or false if option is not set
+func (o Options) Bool(name string) bool {
+	val, _ := o[name].(bool)
+	return val
+}
+
+// Time returns value of TIMESTAMP option or zero time if option is not set
+func (o Options) Time(name string) time.Time {
+	val, _ := o[name].(time.Time)
+	return val
+}
+
+// Duration returns value of DURATION option or 0 if option is not set
+func (o Options) Duration(name string) time.Duration {
+	val, _ := o[name].(time.Duration)
+	return val
+}
+
+// Strings returns checked values of MULTICHECK option or nil if option is not set
+func (o Options) Strings(name string) []string {
+	val, _ := o[name].([]string)
+	return val
+}
+
+// isSet reports whether option has a value, false booleans and empty strings are treated as not set
+func (o Options) isSet(name string) bool {
+	switch val := o[name].(type) {
+	case nil:
+		return false
+	case bool:
+		return val
+	case string:
+		return val != ""
+	case []string:
+		return len(val) != 0
+	default:
+		return true
+	}
+}
+
+// checkRequired verifies that all required options are passed, all missing options are reported at once.
+func checkRequired(opts Options, registered map[string]ConfigOption) error {
+	names := make([]string, 0, len(registered))
+	for name := range registered {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		if _, ok := opts[name]; !ok && registered[name].isRequired() {
+			errs = append(errs, fmt.Errorf("%w: --%s", ErrOptionRequired, name))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// checkDependencies verifies Requires and ConflictsWith constraints of the options,
+// all violations are reported at once.
+func checkDependencies(opts Options, registered map[string]ConfigOption) error {
+	names := make([]string, 0, len(registered))
+	for name := range registered {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		if !opts.isSet(name) {
+			continue
+		}
+
+		requires, conflicts := registered[name].dependencies()
+		for _, other := range requires {
+			if !opts.isSet(other) {
+				errs = append(errs, fmt.Errorf("%w: --%s requires --%s", ErrOptionDependency, name, other))
+			}
+		}
+		for _, other := range conflicts {
+			if opts.isSet(other) {
+				errs = append(errs, fmt.Errorf("%w: --%s conflicts with --%s", ErrOptionDependency, name, other))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// RequiredGroup declares options at least one of which must be set, e.g. either password or SSH key
+type RequiredGroup struct {
+	// Name describes the group in the error message
+	Name string
+	// Options are names of the options in the group
+	Options []string
+}
+
+// checkRequiredGroups verifies that at least one option of every group is set, all violations are reported at once.
+func checkRequiredGroups(opts Options, groups []RequiredGroup) error {
+	var errs []error
+	for _, group := range groups {
+		set := false
+		for _, name := range group.Options {
+			if opts.isSet(name) {
+				set = true
+				break
+			}
+		}
+		if !set {
+			errs = append(errs, fmt.Errorf("%w: %s: one of --%s must be set", ErrRequiredGroup, group.Name, strings.Join(group.Options, ", --")))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,283 @@
+package extcap
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// OptionDefinition is the JSON representation of a config option definition.
+// Functions set with DefaultFunc and Validate can not be represented and are not serialized.
+//
+// Example
+//
+//	{"type": "unsigned", "call": "remote-port", "display": "Remote port", "min": 1, "max": 65535, "default": 22}
+type OptionDefinition struct {
+	Type          string          `json:"type"`
+	Call          string          `json:"call"`
+	Display       string          `json:"display"`
+	Number        *int            `json:"number,omitempty"`
+	Tooltip       string          `json:"tooltip,omitempty"`
+	Group         string          `json:"group,omitempty"`
+	Required      bool            `json:"required,omitempty"`
+	DontSave      bool            `json:"dontSave,omitempty"`
+	Requires      []string        `json:"requires,omitempty"`
+	ConflictsWith []string        `json:"conflictsWith,omitempty"`
+	EnvVar        string          `json:"envVar,omitempty"`
+	Placeholder   string          `json:"placeholder,omitempty"`
+	Validation    string          `json:"validation,omitempty"`
+	Reload        bool            `json:"reload,omitempty"`
+	Min           json.RawMessage `json:"min,omitempty"`
+	Max           json.RawMessage `json:"max,omitempty"`
+	// Default of TIMESTAMP option is number of seconds since epoch, default of DURATION option is a string like "30s"
+	Default json.RawMessage `json:"default,omitempty"`
+	Values  []OptValue      `json:"values,omitempty"`
+}
+
+// MarshalOptions returns JSON representation of config option definitions
+func MarshalOptions(opts []ConfigOption) ([]byte, error) {
+	defs := make([]OptionDefinition, 0, len(opts))
+	for _, opt := range opts {
+		def, err := optionDefinition(opt)
+		if err != nil {
+			return nil, err
+		}
+		defs = append(defs, def)
+	}
+
+	return json.MarshalIndent(defs, "", "  ")
+}
+
+// UnmarshalOptions creates config options from their JSON representation,
+// so an extcap can load its option schema e.g. from an embedded file
+func UnmarshalOptions(data []byte) ([]ConfigOption, error) {
+	var defs []OptionDefinition
+	if err := json.Unmarshal(data, &defs); err != nil {
+		return nil, fmt.Errorf("unable to parse option definitions: %w", err)
+	}
+
+	opts := make([]ConfigOption, 0, len(defs))
+	for _, def := range defs {
+		opt, err := def.option()
+		if err != nil {
+			return nil, fmt.Errorf("option --%s: %w", def.Call, err)
+		}
+		opts = append(opts, opt)
+	}
+
+	return opts, nil
+}
+
+// definition fills the attributes common for all options
+func (c *cfg) definition(optType string) OptionDefinition {
+	def := OptionDefinition{
+		Type:          optType,
+		Call:          c.callValue,
+		Display:       c.displayVal,
+		Tooltip:       c.tooltipVal,
+		Group:         c.group,
+		Required:      c.required,
+		DontSave:      c.dontSave,
+		Requires:      c.requires,
+		ConflictsWith: c.conflicts,
+		EnvVar:        c.envVar,
+	}
+	if c.numberSet {
+		n := c.number
+		def.Number = &n
+	}
+	return def
+}
+
+// apply sets the attributes common for all options
+func (def OptionDefinition) apply(c *cfg) {
+	c.tooltipVal = def.Tooltip
+	c.group = def.Group
+	c.required = def.Required
+	c.dontSave = def.DontSave
+	c.requires = def.Requires
+	c.conflicts = def.ConflictsWith
+	if def.Number != nil {
+		c.number = *def.Number
+		c.numberSet = true
+	}
+}
+
+func optionDefinition(opt ConfigOption) (OptionDefinition, error) {
+	var err error
+	encode := func(set bool, val interface{}) json.RawMessage {
+		if !set || err != nil {
+			return nil
+		}
+		var raw json.RawMessage
+		raw, err = json.Marshal(val)
+		return raw
+	}
+
+	var def OptionDefinition
+	switch o := opt.(type) {
+	case *ConfigIntegerOpt:
+		def = o.definition("integer")
+		def.Min, def.Max, def.Default = encode(o.minSet, o.min), encode(o.maxSet, o.max), encode(o.defaultSet, o.defaultValue)
+	case *ConfigUnsignedOpt:
+		def = o.definition("unsigned")
+		def.Min, def.Max, def.Default = encode(o.minSet, o.min), encode(o.maxSet, o.max), encode(o.defaultSet, o.defaultValue)
+	case *ConfigLongOpt:
+		def = o.definition("long")
+		def.Min, def.Max, def.Default = encode(o.minSet, o.min), encode(o.maxSet, o.max), encode(o.defaultSet, o.defaultValue)
+	case *ConfigDoubleOpt:
+		def = o.definition("double")
+		def.Min, def.Max, def.Default = encode(o.minSet, o.min), encode(o.maxSet, o.max), encode(o.defaultSet, o.defaultValue)
+	case *ConfigStringOpt:
+		def = o.definition("string")
+		def.Placeholder = o.placeholder
+		if o.validation != nil {
+			def.Validation = o.validation.String()
+		}
+		def.Default = encode(o.defaultSet, o.defaultValue)
+	case *ConfigPasswordOpt:
+		def = o.definition("password")
+		def.Placeholder = o.placeholder
+	case *ConfigBoolOpt:
+		def = o.definition("boolflag")
+		def.Default = encode(o.defaultSet, o.defaultValue)
+	case *ConfigTimestampOpt:
+		def = o.definition("timestamp")
+		def.Default = encode(o.defaultSet, o.defaultValue.Unix())
+	case *ConfigDurationOpt:
+		def = o.definition("duration")
+		def.Default = encode(o.defaultSet, o.defaultValue.String())
+	case *ConfigSelectorOpt:
+		def = o.definition("selector")
+		def.Placeholder, def.Reload, def.Values = o.placeholder, o.reload, o.optValues
+	case *ConfigEditSelectorOpt:
+		def = o.definition("editselector")
+		def.Placeholder, def.Reload, def.Values = o.placeholder, o.reload, o.optValues
+		def.Default = encode(o.defaultSet, o.defaultValue)
+	case *ConfigMulticheckOpt:
+		def = o.definition("multicheck")
+		def.Values = o.optValues
+	default:
+		return def, fmt.Errorf("unknown config option type: %T", opt)
+	}
+
+	return def, err
+}
+
+// decode unmarshals raw JSON value, if present, and passes it to set
+func decode[T, R any](raw json.RawMessage, set func(T) R, err *error) {
+	if raw == nil || *err != nil {
+		return
+	}
+	var val T
+	if *err = json.Unmarshal(raw, &val); *err == nil {
+		set(val)
+	}
+}
+
+// withEnv passes environment variable name, if present, to set
+func withEnv[R any](def OptionDefinition, set func(string) R) {
+	if def.EnvVar != "" {
+		set(def.EnvVar)
+	}
+}
+
+func (def OptionDefinition) option() (ConfigOption, error) {
+	var err error
+	var opt ConfigOption
+
+	switch def.Type {
+	case "integer":
+		o := NewConfigIntegerOpt(def.Call, def.Display)
+		decode(def.Min, o.Min, &err)
+		decode(def.Max, o.Max, &err)
+		decode(def.Default, o.Default, &err)
+		withEnv(def, o.EnvVar)
+		def.apply(&o.cfg)
+		opt = o
+	case "unsigned":
+		o := NewConfigUnsignedOpt(def.Call, def.Display)
+		decode(def.Min, o.Min, &err)
+		decode(def.Max, o.Max, &err)
+		decode(def.Default, o.Default, &err)
+		withEnv(def, o.EnvVar)
+		def.apply(&o.cfg)
+		opt = o
+	case "long":
+		o := NewConfigLongOpt(def.Call, def.Display)
+		decode(def.Min, o.Min, &err)
+		decode(def.Max, o.Max, &err)
+		decode(def.Default, o.Default, &err)
+		withEnv(def, o.EnvVar)
+		def.apply(&o.cfg)
+		opt = o
+	case "double":
+		o := NewConfigDoubleOpt(def.Call, def.Display)
+		decode(def.Min, o.Min, &err)
+		decode(def.Max, o.Max, &err)
+		decode(def.Default, o.Default, &err)
+		withEnv(def, o.EnvVar)
+		def.apply(&o.cfg)
+		opt = o
+	case "string":
+		o := NewConfigStringOpt(def.Call, def.Display).Placeholder(def.Placeholder)
+		if def.Validation != "" {
+			o.Validation(def.Validation)
+		}
+		decode(def.Default, o.Default, &err)
+		withEnv(def, o.EnvVar)
+		def.apply(&o.cfg)
+		opt = o
+	case "password":
+		o := NewConfigPasswordOpt(def.Call, def.Display).Placeholder(def.Placeholder)
+		withEnv(def, o.EnvVar)
+		def.apply(&o.cfg)
+		opt = o
+	case "boolflag":
+		o := NewConfigBoolOpt(def.Call, def.Display)
+		decode(def.Default, o.Default, &err)
+		withEnv(def, o.EnvVar)
+		def.apply(&o.cfg)
+		opt = o
+	case "timestamp":
+		o := NewConfigTimestampOpt(def.Call, def.Display)
+		decode(def.Default, func(sec int64) *ConfigTimestampOpt {
+			return o.Default(time.Unix(sec, 0))
+		}, &err)
+		withEnv(def, o.EnvVar)
+		def.apply(&o.cfg)
+		opt = o
+	case "duration":
+		o := NewConfigDurationOpt(def.Call, def.Display)
+		decode(def.Default, func(str string) *ConfigDurationOpt {
+			d, parseErr := time.ParseDuration(str)
+			if parseErr != nil {
+				err = parseErr
+			}
+			return o.Default(d)
+		}, &err)
+		withEnv(def, o.EnvVar)
+		def.apply(&o.cfg)
+		opt = o
+	case "selector":
+		o := NewConfigSelectorOpt(def.Call, def.Display).Placeholder(def.Placeholder).Values(def.Values...)
+		o.reload = def.Reload
+		def.apply(&o.cfg)
+		opt = o
+	case "editselector":
+		o := NewConfigEditSelectorOpt(def.Call, def.Display).Placeholder(def.Placeholder).Values(def.Values...)
+		o.reload = def.Reload
+		decode(def.Default, o.Default, &err)
+		withEnv(def, o.EnvVar)
+		def.apply(&o.cfg)
+		opt = o
+	case "multicheck":
+		o := NewConfigMulticheckOpt(def.Call, def.Display).Values(def.Values...)
+		def.apply(&o.cfg)
+		opt = o
+	default:
+		return nil, fmt.Errorf("unknown config option type: %s", def.Type)
+	}
+
+	return opt, err
+}
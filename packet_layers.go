@@ -0,0 +1,180 @@
+package extcap
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	etherTypeIPv4 = 0x0800
+	etherTypeARP  = 0x0806
+	etherTypeIPv6 = 0x86dd
+
+	ipProtoICMP   = 1
+	ipProtoTCP    = 6
+	ipProtoUDP    = 17
+	ipProtoICMPv6 = 58
+)
+
+// packetLayers are the offsets of the layers found in the packet, -1 if the layer is not present or not parsed
+type packetLayers struct {
+	// ethernet is the offset of the Ethernet header
+	ethernet int
+	// network is the offset of the ARP, IPv4 or IPv6 header
+	network   int
+	etherType uint16
+	// transport is the offset of the transport header, -1 for fragments other than the first one
+	transport int
+	protocol  byte
+	// payload is the offset of the data after the headers which were parsed
+	payload int
+}
+
+// packetLinkOf returns link description for the packet transforms
+func packetLinkOf(dlt DLT) (filterLink, error) {
+	link, err := filterLinkOf(dlt)
+	if err != nil {
+		return filterLink{}, fmt.Errorf("%w: %d", ErrUnsupportedLinkType, dlt.Number)
+	}
+	return link, nil
+}
+
+// parseLayers finds Ethernet (with VLAN tags), ARP, IPv4, IPv6 and transport headers in the packet
+func parseLayers(link filterLink, data []byte) packetLayers {
+	l := packetLayers{ethernet: -1, network: -1, transport: -1, payload: -1}
+	if link.ethernet {
+		if len(data) < 14 {
+			return l
+		}
+		l.ethernet = 0
+		off := 12
+		l.etherType = binary.BigEndian.Uint16(data[off:])
+		for l.etherType == 0x8100 || l.etherType == 0x88a8 || l.etherType == 0x9100 {
+			off += 4
+			if len(data) < off+2 {
+				return l
+			}
+			l.etherType = binary.BigEndian.Uint16(data[off:])
+		}
+		l.network = off + 2
+	} else {
+		if len(data) < 1 {
+			return l
+		}
+		l.network = 0
+		switch data[0] >> 4 {
+		case 4:
+			l.etherType = etherTypeIPv4
+		case 6:
+			l.etherType = etherTypeIPv6
+		default:
+			l.network = -1
+			return l
+		}
+	}
+
+	n := l.network
+	switch l.etherType {
+	case etherTypeARP:
+		if len(data) < n+8 {
+			l.network = -1
+		} else {
+			l.payload = len(data)
+		}
+	case etherTypeIPv4:
+		if len(data) < n+20 || data[n]>>4 != 4 {
+			l.network = -1
+			return l
+		}
+		l.protocol = data[n+9]
+		l.payload = n + int(data[n]&0x0f)*4
+		if binary.BigEndian.Uint16(data[n+6:])&0x1fff == 0 {
+			l.transport = l.payload
+		}
+	case etherTypeIPv6:
+		if len(data) < n+40 || data[n]>>4 != 6 {
+			l.network = -1
+			return l
+		}
+		next, off := data[n+6], n+40
+		for {
+			switch next {
+			case 0, 43, 60:
+				if len(data) < off+8 {
+					// the previous header may claim more than was captured
+					l.payload = min(off, len(data))
+					return l
+				}
+				next, off = data[off], off+(int(data[off+1])+1)*8
+				continue
+			case 44:
+				if len(data) < off+8 {
+					return l
+				}
+				if binary.BigEndian.Uint16(data[off+2:])&0xfff8 != 0 {
+					l.payload = off + 8
+					return l
+				}
+				next, off = data[off], off+8
+				continue
+			}
+			break
+		}
+		l.protocol, l.transport, l.payload = next, off, off
+	default:
+		l.network = -1
+	}
+	if l.transport >= 0 {
+		l.payload = l.transport + transportHeaderLen(l.protocol, data, l.transport)
+	}
+	l.payload = min(l.payload, len(data))
+	return l
+}
+
+// transportHeaderLen returns length of the transport header at the offset, 0 if the protocol is not known
+// or the header is truncated
+func transportHeaderLen(protocol byte, data []byte, off int) int {
+	var n int
+	switch protocol {
+	case ipProtoTCP:
+		if len(data) < off+20 {
+			return 0
+		}
+		n = int(data[off+12]>>4) * 4
+	case ipProtoUDP, ipProtoICMP, ipProtoICMPv6:
+		n = 8
+	default:
+		return 0
+	}
+	if n < 8 || len(data) < off+n {
+		return 0
+	}
+	return n
+}
+
+// checksum computes the internet checksum of the data
+func checksum(data []byte) uint16 {
+	var sum uint32
+	for ; len(data) > 1; data = data[2:] {
+		sum += uint32(binary.BigEndian.Uint16(data))
+	}
+	if len(data) == 1 {
+		sum += uint32(data[0]) << 8
+	}
+	for sum > 0xffff {
+		sum = sum>>16 + sum&0xffff
+	}
+	return ^uint16(sum)
+}
+
+// updateChecksum incrementally updates the checksum at the offset after bytes old were replaced with new (RFC 1624)
+func updateChecksum(data []byte, off int, old, new []byte) {
+	sum := uint32(^binary.BigEndian.Uint16(data[off:]))
+	for i := 0; i+1 < len(old); i += 2 {
+		sum += uint32(^binary.BigEndian.Uint16(old[i:])) + uint32(binary.BigEndian.Uint16(new[i:]))
+	}
+	for sum > 0xffff {
+		sum = sum>>16 + sum&0xffff
+	}
+	binary.BigEndian.PutUint16(data[off:], ^uint16(sum))
+}
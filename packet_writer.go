@@ -0,0 +1,343 @@
+package extcap
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FormatEnvVar is the environment variable overriding format chosen by AutoFormat, "pcap" or "pcapng"
+const FormatEnvVar = "EXTCAP_FORMAT"
+
+// pcapngMinVersion is the oldest Wireshark version AutoFormat writes pcapng for
+var pcapngMinVersion = [2]int{3, 0}
+
+// PacketWriter writes captured packets to the fifo in the capture file format.
+// The built-in writers also implement io.Closer, Close flushes buffered data, writes the trailer of the format
+// and returns the first write error. It is safe to call more than once, packets can not be written after it.
+type PacketWriter interface {
+	// WritePacket writes packet captured at ts. Data may be truncated, origLen is the length of the packet on the wire.
+	WritePacket(ts time.Time, data []byte, origLen int) error
+}
+
+// Packet is a captured packet of a batch written with WritePackets
+type Packet struct {
+	// Timestamp is the time the packet was captured
+	Timestamp time.Time
+	// Data are the captured bytes of the packet
+	Data []byte
+	// OrigLen is the length of the packet on the wire
+	OrigLen int
+}
+
+// BatchWriter is implemented by writers framing a batch of packets with minimal syscalls, like PcapWriter and PcapngWriter
+type BatchWriter interface {
+	WritePackets(packets []Packet) error
+}
+
+// WritePackets writes the batch with w.WritePackets if w is BatchWriter and packet by packet otherwise
+func WritePackets(w PacketWriter, packets []Packet) error {
+	if bw, ok := w.(BatchWriter); ok {
+		return bw.WritePackets(packets)
+	}
+	for _, p := range packets {
+		if err := w.WritePacket(p.Timestamp, p.Data, p.OrigLen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PacketWriterFunc creates PacketWriter writing to w packets of the link type dlt.
+// Options passed by the library, e.g. SectionApplication, come first, so the function can override them.
+type PacketWriterFunc func(w io.Writer, dlt DLT, opts ...WriterOption) (PacketWriter, error)
+
+// WriterOption configures the built-in packet writers
+type WriterOption func(*writerConfig)
+
+type writerConfig struct {
+	snaplen       int
+	nanosecond    bool
+	statsInterval time.Duration
+	byteOrder     binary.ByteOrder
+	tsMode        TimestampMode
+	corrector     ClockCorrector
+	// base is the creation time of the writer for TimestampMonotonic
+	base time.Time
+
+	// section header options
+	hardware    string
+	os          string
+	application string
+
+	// options of the first pcapng interface
+	iface InterfaceInfo
+
+	// Wireshark version passed with --extcap-version
+	hostVersion string
+}
+
+// truncate cuts data to snaplen and corrects origLen shorter than data
+func (cfg *writerConfig) truncate(data []byte, origLen int) ([]byte, int) {
+	if origLen < len(data) {
+		origLen = len(data)
+	}
+	if len(data) > cfg.snaplen {
+		data = data[:cfg.snaplen]
+	}
+	return data, origLen
+}
+
+func newWriterConfig(opts []WriterOption) writerConfig {
+	cfg := writerConfig{snaplen: DefaultSnaplen, byteOrder: binary.LittleEndian, base: time.Now()}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// SnapshotLength sets maximum number of bytes of packet data written, longer packets are truncated
+// while the original length is kept. Values not greater than 0 are ignored, DefaultSnaplen is used by default.
+func SnapshotLength(snaplen int) WriterOption {
+	return func(cfg *writerConfig) {
+		if snaplen > 0 {
+			cfg.snaplen = snaplen
+		}
+	}
+}
+
+// NanosecondResolution makes the writer keep timestamps with nanosecond precision instead of microseconds
+func NanosecondResolution() WriterOption {
+	return func(cfg *writerConfig) {
+		cfg.nanosecond = true
+	}
+}
+
+// MicrosecondResolution makes the writer keep timestamps with microsecond precision, which is the default.
+// It overrides NanosecondResolution passed before it.
+func MicrosecondResolution() WriterOption {
+	return func(cfg *writerConfig) {
+		cfg.nanosecond = false
+	}
+}
+
+// ByteOrder sets byte order of the pcap writer output, e.g. binary.BigEndian for tools reading only the native order
+// of another machine. Output is little-endian by default, the pcapng writer always writes little-endian.
+func ByteOrder(order binary.ByteOrder) WriterOption {
+	return func(cfg *writerConfig) {
+		if order != nil {
+			cfg.byteOrder = order
+		}
+	}
+}
+
+// StatisticsInterval makes the pcapng writer write Interface Statistics Blocks when a packet is written
+// and interval has passed since the previous ones, so Wireshark shows drop statistics during the capture
+func StatisticsInterval(interval time.Duration) WriterOption {
+	return func(cfg *writerConfig) {
+		cfg.statsInterval = interval
+	}
+}
+
+// SectionHardware sets shb_hardware option of the pcapng section, the hardware the capture runs on
+func SectionHardware(hardware string) WriterOption {
+	return func(cfg *writerConfig) {
+		cfg.hardware = hardware
+	}
+}
+
+// SectionOS sets shb_os option of the pcapng section, the operating system the capture runs on
+func SectionOS(os string) WriterOption {
+	return func(cfg *writerConfig) {
+		cfg.os = os
+	}
+}
+
+// SectionApplication sets shb_userappl option of the pcapng section, the application which created the capture.
+// App passes its name and version by default.
+func SectionApplication(application string) WriterOption {
+	return func(cfg *writerConfig) {
+		cfg.application = application
+	}
+}
+
+// FirstInterface sets options of the interface created by NewPcapngWriter.
+// App passes name of the capture interface and the capture filter by default.
+func FirstInterface(info InterfaceInfo) WriterOption {
+	return func(cfg *writerConfig) {
+		cfg.iface = info
+	}
+}
+
+// HostVersion sets Wireshark version the output is written for, used by AutoFormat.
+// App passes value of --extcap-version by default.
+func HostVersion(version string) WriterOption {
+	return func(cfg *writerConfig) {
+		cfg.hostVersion = version
+	}
+}
+
+// AutoFormat is PacketWriterFunc for App.NewPacketWriter choosing the format by Wireshark version.
+// Wireshark 3.0 and newer gets pcapng, older versions, which may not pass --extcap-version, get legacy pcap.
+// The choice can be overridden with FormatEnvVar.
+func AutoFormat(w io.Writer, dlt DLT, opts ...WriterOption) (PacketWriter, error) {
+	switch os.Getenv(FormatEnvVar) {
+	case "pcap":
+		return PcapFormat(w, dlt, opts...)
+	case "pcapng":
+		return PcapngFormat(w, dlt, opts...)
+	}
+
+	cfg := newWriterConfig(opts)
+	if supportsPcapng(cfg.hostVersion) {
+		return PcapngFormat(w, dlt, opts...)
+	}
+	return PcapFormat(w, dlt, opts...)
+}
+
+// supportsPcapng checks if Wireshark version "major.minor" is at least pcapngMinVersion
+func supportsPcapng(version string) bool {
+	majorStr, minorStr, _ := strings.Cut(version, ".")
+	major, err := strconv.Atoi(majorStr)
+	if err != nil {
+		return false
+	}
+	minor, _ := strconv.Atoi(minorStr)
+	if major != pcapngMinVersion[0] {
+		return major > pcapngMinVersion[0]
+	}
+	return minor >= pcapngMinVersion[1]
+}
+
+// countingWriter counts written packets in the session statistics
+type countingWriter struct {
+	PacketWriter
+	session *CaptureSession
+}
+
+func (w countingWriter) WritePacket(ts time.Time, data []byte, origLen int) error {
+	if w.session.autostopReached() {
+		return ErrAutostop
+	}
+	if err := w.PacketWriter.WritePacket(ts, data, origLen); err != nil {
+		if errors.Is(err, ErrPipeClosed) && w.session.cancel != nil {
+			w.session.cancel(err)
+		}
+		return err
+	}
+
+	w.session.CountPacket(len(data))
+	return nil
+}
+
+func (w countingWriter) WritePackets(packets []Packet) error {
+	if w.session.autostopReached() {
+		return ErrAutostop
+	}
+	if limit := w.session.autostop.Packets; limit > 0 {
+		// the batch is cut to the packet limit, the byte limit is checked after it
+		remaining := limit - w.session.packets.Load()
+		if uint64(len(packets)) > remaining {
+			packets = packets[:remaining]
+		}
+	}
+	if err := WritePackets(w.PacketWriter, packets); err != nil {
+		if errors.Is(err, ErrPipeClosed) && w.session.cancel != nil {
+			w.session.cancel(err)
+		}
+		return err
+	}
+
+	for _, p := range packets {
+		w.session.CountPacket(len(p.Data))
+	}
+	return nil
+}
+
+// ReportDropped counts the drops in the session statistics and reports them to the wrapped writer
+func (w countingWriter) ReportDropped(id int, n uint64) error {
+	w.session.dropped.Add(n)
+	return reportDropped(w.PacketWriter, id, n)
+}
+
+// Flush flushes the wrapped writer if it buffers data
+func (w countingWriter) Flush() error {
+	return pipeError(flushWriter(w.PacketWriter))
+}
+
+// Close closes the wrapped writer if it is io.Closer
+func (w countingWriter) Close() error {
+	return closeWriter(w.PacketWriter)
+}
+
+// flusher is implemented by writers buffering data, like BufferedWriter
+type flusher interface {
+	Flush() error
+}
+
+// reportDropped reports the drops to w if it accounts them, like PcapngWriter
+func reportDropped(w PacketWriter, id int, n uint64) error {
+	if r, ok := w.(dropReporter); ok {
+		return r.ReportDropped(id, n)
+	}
+	return nil
+}
+
+// flushWriter flushes w if it buffers data
+func flushWriter(w PacketWriter) error {
+	if f, ok := w.(flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// closeWriter closes w if it is io.Closer
+func closeWriter(w PacketWriter) error {
+	if c, ok := w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// writeRecord writes header and data of a packet record. Files, like the fifo, get them with a single vectored write,
+// other writers get them copied to scratch and written with a single Write. It returns the scratch buffer to reuse.
+func writeRecord(w io.Writer, scratch, header, data, trailer []byte) ([]byte, error) {
+	if f, ok := w.(*os.File); ok {
+		return scratch, pipeError(writev(f, [][]byte{header, data, trailer}))
+	}
+
+	scratch = append(append(append(scratch[:0], header...), data...), trailer...)
+	_, err := w.Write(scratch)
+	return scratch, pipeError(err)
+}
+
+// writeBuffers writes bufs with a single vectored write to files and a single Write to other writers,
+// see writeRecord
+func writeBuffers(w io.Writer, scratch []byte, bufs [][]byte) ([]byte, error) {
+	if f, ok := w.(*os.File); ok {
+		return scratch, pipeError(writev(f, bufs))
+	}
+
+	scratch = scratch[:0]
+	for _, b := range bufs {
+		scratch = append(scratch, b...)
+	}
+	_, err := w.Write(scratch)
+	return scratch, pipeError(err)
+}
+
+// consumeBuffers drops first n written bytes from bufs
+func consumeBuffers(bufs [][]byte, n int) [][]byte {
+	for len(bufs) > 0 && n >= len(bufs[0]) {
+		n -= len(bufs[0])
+		bufs = bufs[1:]
+	}
+	if len(bufs) > 0 {
+		bufs[0] = bufs[0][n:]
+	}
+	return bufs
+}
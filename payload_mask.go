@@ -0,0 +1,51 @@
+package extcap
+
+import (
+	"fmt"
+	"time"
+)
+
+// PayloadPolicy tells what MaskPayload does with the payload after the transport header
+type PayloadPolicy string
+
+// Payload policies, the values can be used as values of a selector config option
+const (
+	// PayloadKeep writes the payload unchanged
+	PayloadKeep PayloadPolicy = "keep"
+	// PayloadZero overwrites the payload with zeros, the packet length is kept
+	PayloadZero PayloadPolicy = "zero"
+	// PayloadTruncate removes the payload, the original length of the packet is kept
+	PayloadTruncate PayloadPolicy = "truncate"
+)
+
+// MaskPayload returns transform applying the policy to IPv4 and IPv6 packets with the link type.
+// The payload starts after the TCP, UDP or ICMP header, after the IP headers for other protocols and fragments.
+// Checksums are not updated. Other packets are not changed.
+func MaskPayload(dlt DLT, policy PayloadPolicy) (Transform, error) {
+	link, err := packetLinkOf(dlt)
+	if err != nil {
+		return nil, err
+	}
+
+	switch policy {
+	case PayloadKeep:
+		return func(ts time.Time, data []byte) (time.Time, []byte, bool) {
+			return ts, data, true
+		}, nil
+	case PayloadZero, PayloadTruncate:
+	default:
+		return nil, fmt.Errorf("%w: payload policy %q", ErrValueInvalid, policy)
+	}
+
+	return func(ts time.Time, data []byte) (time.Time, []byte, bool) {
+		l := parseLayers(link, data)
+		if l.payload < 0 || (l.etherType != etherTypeIPv4 && l.etherType != etherTypeIPv6) {
+			return ts, data, true
+		}
+		if policy == PayloadTruncate {
+			return ts, data[:l.payload], true
+		}
+		clear(data[l.payload:])
+		return ts, data, true
+	}, nil
+}
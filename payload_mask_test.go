@@ -0,0 +1,47 @@
+package extcap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaskPayload(t *testing.T) {
+	// IPv4 UDP with 4 bytes payload
+	frame := []byte{
+		0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 0x08, 0x00,
+		0x45, 0, 0, 32, 0, 0, 0x40, 0, 64, 17, 0, 0,
+		10, 0, 0, 1, 192, 168, 1, 2,
+		0, 53, 0x14, 0xe9, 0, 12, 0, 0,
+		1, 2, 3, 4,
+	}
+
+	truncate, err := MaskPayload(DLT{Number: 1}, PayloadTruncate)
+	require.NoError(t, err)
+	_, out, keep := truncate(time.Now(), append([]byte(nil), frame...))
+	assert.True(t, keep)
+	assert.Equal(t, frame[:42], out)
+
+	zero, err := MaskPayload(DLT{Number: 1}, PayloadZero)
+	require.NoError(t, err)
+	_, out, _ = zero(time.Now(), append([]byte(nil), frame...))
+	assert.Equal(t, append(frame[:42:42], 0, 0, 0, 0), out)
+
+	_, out, _ = zero(time.Now(), append([]byte(nil), filterARP...))
+	assert.Equal(t, filterARP, out)
+
+	// IPv6 hop-by-hop header claiming more than was captured, followed by destination options
+	ipv6 := make([]byte, 60)
+	ipv6[0], ipv6[6], ipv6[40], ipv6[41] = 0x60, 0, 60, 255
+	for _, policy := range []PayloadPolicy{PayloadTruncate, PayloadZero} {
+		mask, err := MaskPayload(DLT{Number: 101}, policy)
+		require.NoError(t, err)
+		_, out, _ = mask(time.Now(), append([]byte(nil), ipv6...))
+		assert.Equal(t, ipv6, out)
+	}
+
+	_, err = MaskPayload(DLT{Number: 1}, "shred")
+	assert.ErrorIs(t, err, ErrValueInvalid)
+}
@@ -0,0 +1,174 @@
+package extcap
+
+import (
+	"io"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DefaultSnaplen is the maximum length of captured packet data used by the built-in writers
+const DefaultSnaplen = 262144
+
+const (
+	pcapMagicMicroseconds = 0xa1b2c3d4
+	pcapMagicNanoseconds  = 0xa1b23c4d
+	pcapVersionMajor      = 2
+	pcapVersionMinor      = 4
+)
+
+// PcapWriter writes packets in the legacy pcap format, see https://www.ietf.org/archive/id/draft-gharris-opsawg-pcap-01.html
+type PcapWriter struct {
+	w   io.Writer
+	cfg writerConfig
+	mu  sync.Mutex
+	// err is the first write error, guarded by mu
+	err    error
+	closed bool
+	// header, batch, bufs and scratch are reused for records, guarded by mu
+	header  [16]byte
+	batch   []byte
+	bufs    [][]byte
+	scratch []byte
+}
+
+// NewPcapWriter writes pcap file header for the link type of dlt to w
+func NewPcapWriter(w io.Writer, dlt DLT, opts ...WriterOption) (*PcapWriter, error) {
+	pw := &PcapWriter{w: w, cfg: newWriterConfig(opts)}
+
+	magic := uint32(pcapMagicMicroseconds)
+	if pw.cfg.nanosecond {
+		magic = pcapMagicNanoseconds
+	}
+
+	// readers detect the byte order from the magic
+	order := pw.cfg.byteOrder
+	header := make([]byte, 24)
+	order.PutUint32(header[0:], magic)
+	order.PutUint16(header[4:], pcapVersionMajor)
+	order.PutUint16(header[6:], pcapVersionMinor)
+	// thiszone and sigfigs are always 0
+	order.PutUint32(header[16:], uint32(pw.cfg.snaplen))
+	order.PutUint32(header[20:], uint32(dlt.Number))
+
+	if _, err := w.Write(header); err != nil {
+		return nil, pipeError(err)
+	}
+
+	return pw, nil
+}
+
+// PcapFormat is PacketWriterFunc for App.NewPacketWriter creating PcapWriter
+func PcapFormat(w io.Writer, dlt DLT, opts ...WriterOption) (PacketWriter, error) {
+	return NewPcapWriter(w, dlt, opts...)
+}
+
+// WritePacket writes packet record, data longer than snaplen is truncated and origLen shorter than data is corrected.
+// It is safe to call from multiple goroutines, every record is written with a single Write or writev.
+func (pw *PcapWriter) WritePacket(ts time.Time, data []byte, origLen int) error {
+	data, origLen = pw.cfg.truncate(data, origLen)
+
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+
+	if err := pw.checkLocked(); err != nil {
+		return err
+	}
+
+	// header is kept in the array, so writing packets does not allocate
+	pw.putHeader(pw.header[:], ts, len(data), origLen)
+
+	var err error
+	if pw.scratch, err = writeRecord(pw.w, pw.scratch, pw.header[:], data, nil); err != nil {
+		pw.err = err
+	}
+	return err
+}
+
+// WritePackets writes records of all packets with a single Write or writev, see WritePacket
+func (pw *PcapWriter) WritePackets(packets []Packet) error {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+
+	if err := pw.checkLocked(); err != nil {
+		return err
+	}
+
+	// headers has the capacity for the whole batch, so the earlier headers are not moved by reallocation
+	headers := slices.Grow(pw.batch[:0], 16*len(packets))
+	bufs := pw.bufs[:0]
+	for _, p := range packets {
+		data, origLen := pw.cfg.truncate(p.Data, p.OrigLen)
+		start := len(headers)
+		headers = headers[:start+16]
+		pw.putHeader(headers[start:], p.Timestamp, len(data), origLen)
+		bufs = append(bufs, headers[start:], data)
+	}
+	pw.batch, pw.bufs = headers, bufs
+
+	var err error
+	if pw.scratch, err = writeBuffers(pw.w, pw.scratch, bufs); err != nil {
+		pw.err = err
+	}
+	return err
+}
+
+// putHeader puts record header of the packet to b
+func (pw *PcapWriter) putHeader(b []byte, ts time.Time, length, origLen int) {
+	ts = pw.cfg.timestamp(ts)
+	fraction := ts.Nanosecond() / 1000
+	if pw.cfg.nanosecond {
+		fraction = ts.Nanosecond()
+	}
+
+	order := pw.cfg.byteOrder
+	order.PutUint32(b[0:], uint32(ts.Unix()))
+	order.PutUint32(b[4:], uint32(fraction))
+	order.PutUint32(b[8:], uint32(length))
+	order.PutUint32(b[12:], uint32(origLen))
+}
+
+// checkLocked returns error if writing is not possible anymore
+func (pw *PcapWriter) checkLocked() error {
+	if pw.err != nil {
+		return pw.err
+	}
+	if pw.closed {
+		return ErrWriterClosed
+	}
+	return nil
+}
+
+// Flush writes data buffered by the underlying writer, e.g. BufferedWriter, to the fifo
+func (pw *PcapWriter) Flush() error {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+
+	if err := pw.checkLocked(); err != nil {
+		return err
+	}
+	return pw.flushLocked()
+}
+
+// Close flushes the underlying writer, which is not closed. It returns the first write error of the writer,
+// calling it again returns the same result.
+func (pw *PcapWriter) Close() error {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+
+	if !pw.closed && pw.err == nil {
+		_ = pw.flushLocked()
+	}
+	pw.closed = true
+	return pw.err
+}
+
+func (pw *PcapWriter) flushLocked() error {
+	if f, ok := pw.w.(flusher); ok {
+		if err := f.Flush(); err != nil {
+			pw.err = pipeError(err)
+			return pw.err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,538 @@
+package extcap
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPcapWriter(t *testing.T) {
+	buf := new(bytes.Buffer)
+	pw, err := NewPcapWriter(buf, DLT{Number: 147, Name: "USER0"})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{
+		0xd4, 0xc3, 0xb2, 0xa1, 2, 0, 4, 0,
+		0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 4, 0, 147, 0, 0, 0,
+	}, buf.Bytes())
+
+	buf.Reset()
+	ts := time.Unix(1700000000, 123456789)
+	assert.NoError(t, pw.WritePacket(ts, []byte{1, 2, 3}, 60))
+	assert.Equal(t, []byte{
+		0x00, 0xf1, 0x53, 0x65, 0x40, 0xe2, 0x01, 0x00,
+		3, 0, 0, 0, 60, 0, 0, 0,
+		1, 2, 3,
+	}, buf.Bytes())
+}
+
+func TestPcapWriterSnaplen(t *testing.T) {
+	buf := new(bytes.Buffer)
+	pw, err := NewPcapWriter(buf, DLT{Number: 1})
+	assert.NoError(t, err)
+
+	buf.Reset()
+	assert.NoError(t, pw.WritePacket(time.Unix(0, 0), make([]byte, DefaultSnaplen+10), 0))
+	assert.Equal(t, 16+DefaultSnaplen, buf.Len())
+	assert.Equal(t, []byte{0, 0, 4, 0, 10, 0, 4, 0}, buf.Bytes()[8:16])
+}
+
+func TestPcapWriterByteOrder(t *testing.T) {
+	buf := new(bytes.Buffer)
+	pw, err := NewPcapWriter(buf, DLT{Number: 147}, NanosecondResolution(), ByteOrder(binary.BigEndian))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{
+		0xa1, 0xb2, 0x3c, 0x4d, 0, 2, 0, 4,
+		0, 0, 0, 0, 0, 0, 0, 0,
+		0, 4, 0, 0, 0, 0, 0, 147,
+	}, buf.Bytes())
+
+	buf.Reset()
+	assert.NoError(t, pw.WritePacket(time.Unix(1700000000, 123456789), []byte{1, 2, 3}, 60))
+	assert.Equal(t, []byte{
+		0x65, 0x53, 0xf1, 0x00, 0x07, 0x5b, 0xcd, 0x15,
+		0, 0, 0, 3, 0, 0, 0, 60,
+		1, 2, 3,
+	}, buf.Bytes())
+
+	buf.Reset()
+	_, err = NewPcapWriter(buf, DLT{Number: 1}, NanosecondResolution(), MicrosecondResolution())
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0xd4, 0xc3, 0xb2, 0xa1}, buf.Bytes()[:4])
+}
+
+func TestPcapngWriter(t *testing.T) {
+	buf := new(bytes.Buffer)
+	pw, err := NewPcapngWriter(buf, DLT{Number: 147, Name: "USER0"})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{
+		// Section Header Block
+		0x0a, 0x0d, 0x0d, 0x0a, 28, 0, 0, 0,
+		0x4d, 0x3c, 0x2b, 0x1a, 1, 0, 0, 0,
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		28, 0, 0, 0,
+		// Interface Description Block
+		1, 0, 0, 0, 20, 0, 0, 0,
+		147, 0, 0, 0, 0, 0, 4, 0,
+		20, 0, 0, 0,
+	}, buf.Bytes())
+
+	buf.Reset()
+	ts := time.Unix(1700000000, 123456789)
+	assert.NoError(t, pw.WritePacket(ts, []byte{1, 2, 3, 4, 5}, 60))
+	assert.Equal(t, []byte{
+		6, 0, 0, 0, 40, 0, 0, 0,
+		0, 0, 0, 0,
+		0x24, 0x0a, 0x06, 0x00, 0x40, 0x22, 0x20, 0x18,
+		5, 0, 0, 0, 60, 0, 0, 0,
+		1, 2, 3, 4, 5, 0, 0, 0,
+		40, 0, 0, 0,
+	}, buf.Bytes())
+}
+
+func TestNanosecondResolution(t *testing.T) {
+	ts := time.Unix(1700000000, 123456789)
+
+	buf := new(bytes.Buffer)
+	pw, err := NewPcapWriter(buf, DLT{Number: 1}, NanosecondResolution())
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x4d, 0x3c, 0xb2, 0xa1}, buf.Bytes()[:4])
+	buf.Reset()
+	assert.NoError(t, pw.WritePacket(ts, nil, 0))
+	assert.Equal(t, []byte{0x15, 0xcd, 0x5b, 0x07}, buf.Bytes()[4:8])
+
+	buf.Reset()
+	ng, err := NewPcapngWriter(buf, DLT{Number: 1}, NanosecondResolution())
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{
+		1, 0, 0, 0, 32, 0, 0, 0,
+		1, 0, 0, 0, 0, 0, 4, 0,
+		9, 0, 1, 0, 9, 0, 0, 0,
+		0, 0, 0, 0,
+		32, 0, 0, 0,
+	}, buf.Bytes()[28:])
+	buf.Reset()
+	assert.NoError(t, ng.WritePacket(ts, nil, 0))
+	units := uint64(ts.UnixNano())
+	assert.Equal(t, uint32(units>>32), binary.LittleEndian.Uint32(buf.Bytes()[12:]))
+	assert.Equal(t, uint32(units), binary.LittleEndian.Uint32(buf.Bytes()[16:]))
+}
+
+func TestPcapngMultipleInterfaces(t *testing.T) {
+	buf := new(bytes.Buffer)
+	pw, err := NewPcapngWriter(buf, DLT{Number: 1})
+	assert.NoError(t, err)
+
+	buf.Reset()
+	id, err := pw.AddInterface(DLT{Number: 105}, "wlan0")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, id)
+	assert.Equal(t, []byte{
+		1, 0, 0, 0, 36, 0, 0, 0,
+		105, 0, 0, 0, 0, 0, 4, 0,
+		2, 0, 5, 0, 'w', 'l', 'a', 'n', '0', 0, 0, 0,
+		0, 0, 0, 0,
+		36, 0, 0, 0,
+	}, buf.Bytes())
+
+	buf.Reset()
+	assert.NoError(t, pw.WriteInterfacePacket(1, time.Unix(0, 0), []byte{1}, 1))
+	assert.Equal(t, uint32(1), binary.LittleEndian.Uint32(buf.Bytes()[8:]))
+
+	assert.ErrorIs(t, pw.WriteInterfacePacket(2, time.Unix(0, 0), []byte{1}, 1), ErrUnknownInterface)
+}
+
+func TestPcapngPacketFlags(t *testing.T) {
+	buf := new(bytes.Buffer)
+	pw, err := NewPcapngWriter(buf, DLT{Number: 1})
+	assert.NoError(t, err)
+
+	buf.Reset()
+	assert.NoError(t, pw.WritePacketMeta(PacketMeta{Timestamp: time.Unix(0, 0), Flags: FlagOutbound | FlagCRCError}, []byte{1, 2, 3, 4}))
+	assert.Equal(t, []byte{
+		6, 0, 0, 0, 48, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		4, 0, 0, 0, 4, 0, 0, 0,
+		1, 2, 3, 4,
+		2, 0, 4, 0, 2, 0, 0, 0x80,
+		0, 0, 0, 0,
+		48, 0, 0, 0,
+	}, buf.Bytes())
+}
+
+func TestPcapngDrops(t *testing.T) {
+	buf := new(bytes.Buffer)
+	pw, err := NewPcapngWriter(buf, DLT{Number: 1}, StatisticsInterval(time.Minute))
+	assert.NoError(t, err)
+
+	assert.NoError(t, pw.ReportDropped(0, 3))
+	assert.ErrorIs(t, pw.ReportDropped(1, 1), ErrUnknownInterface)
+
+	buf.Reset()
+	ts := time.Unix(60, 0)
+	assert.NoError(t, pw.WritePacket(ts, []byte{1, 2, 3, 4}, 4))
+	assert.Equal(t, []byte{
+		// Enhanced Packet Block with epb_dropcount
+		6, 0, 0, 0, 52, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0x87, 0x93, 0x03,
+		4, 0, 0, 0, 4, 0, 0, 0,
+		1, 2, 3, 4,
+		4, 0, 8, 0, 3, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0,
+		52, 0, 0, 0,
+		// Interface Statistics Block with isb_ifrecv and isb_ifdrop
+		5, 0, 0, 0, 52, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0x87, 0x93, 0x03,
+		4, 0, 8, 0, 4, 0, 0, 0, 0, 0, 0, 0,
+		5, 0, 8, 0, 3, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0,
+		52, 0, 0, 0,
+	}, buf.Bytes())
+
+	// no drops since the previous packet and statistics are not due yet
+	buf.Reset()
+	assert.NoError(t, pw.WritePacket(ts.Add(time.Second), []byte{1, 2, 3, 4}, 4))
+	assert.Equal(t, 36, buf.Len())
+}
+
+func TestPcapngPacketComment(t *testing.T) {
+	buf := new(bytes.Buffer)
+	pw, err := NewPcapngWriter(buf, DLT{Number: 1})
+	assert.NoError(t, err)
+
+	buf.Reset()
+	assert.NoError(t, pw.WritePacketComment(time.Unix(0, 0), []byte{1, 2, 3, 4}, 4, "hello"))
+	assert.Equal(t, []byte{
+		1, 0, 5, 0, 'h', 'e', 'l', 'l', 'o', 0, 0, 0,
+		0, 0, 0, 0,
+	}, buf.Bytes()[32:48])
+}
+
+func TestPcapngNameResolution(t *testing.T) {
+	buf := new(bytes.Buffer)
+	pw, err := NewPcapngWriter(buf, DLT{Number: 1})
+	assert.NoError(t, err)
+
+	buf.Reset()
+	assert.NoError(t, pw.WriteNameResolution(NameRecord{IP: net.ParseIP("10.0.0.1"), Names: []string{"gw"}}))
+	assert.Equal(t, []byte{
+		4, 0, 0, 0, 28, 0, 0, 0,
+		1, 0, 7, 0, 10, 0, 0, 1, 'g', 'w', 0, 0,
+		0, 0, 0, 0,
+		28, 0, 0, 0,
+	}, buf.Bytes())
+
+	buf.Reset()
+	assert.NoError(t, pw.WriteNameResolution(NameRecord{IP: net.ParseIP("::1"), Names: []string{"lo"}}))
+	assert.Equal(t, []byte{2, 0, 19, 0}, buf.Bytes()[8:12])
+
+	assert.ErrorIs(t, pw.WriteNameResolution(NameRecord{Names: []string{"none"}}), ErrValueInvalid)
+}
+
+func TestPcapngDecryptionSecrets(t *testing.T) {
+	buf := new(bytes.Buffer)
+	pw, err := NewPcapngWriter(buf, DLT{Number: 1})
+	assert.NoError(t, err)
+
+	buf.Reset()
+	assert.NoError(t, pw.WriteDecryptionSecrets(SecretsTLSKeyLog, []byte("CLIENT_RANDOM")))
+	assert.Equal(t, []byte{
+		0x0a, 0, 0, 0, 36, 0, 0, 0,
+		0x4b, 0x53, 0x4c, 0x54, 13, 0, 0, 0,
+		'C', 'L', 'I', 'E', 'N', 'T', '_', 'R', 'A', 'N', 'D', 'O', 'M', 0, 0, 0,
+		36, 0, 0, 0,
+	}, buf.Bytes())
+}
+
+func TestPcapngCustomBlock(t *testing.T) {
+	buf := new(bytes.Buffer)
+	pw, err := NewPcapngWriter(buf, DLT{Number: 1})
+	assert.NoError(t, err)
+
+	buf.Reset()
+	assert.NoError(t, pw.WriteCustomBlock(32473, []byte{1, 2, 3}, true))
+	assert.Equal(t, []byte{
+		0xad, 0x0b, 0, 0, 20, 0, 0, 0,
+		0xd9, 0x7e, 0, 0, 1, 2, 3, 0,
+		20, 0, 0, 0,
+	}, buf.Bytes())
+
+	buf.Reset()
+	assert.NoError(t, pw.WriteBlock(0x80000001, []byte{9}))
+	assert.Equal(t, []byte{1, 0, 0, 0x80, 16, 0, 0, 0, 9, 0, 0, 0, 16, 0, 0, 0}, buf.Bytes())
+}
+
+func TestPcapngSectionOptions(t *testing.T) {
+	buf := new(bytes.Buffer)
+	_, err := NewPcapngWriter(buf, DLT{Number: 1}, SectionApplication("app 1.0"), SectionOS("linux"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{
+		0x0a, 0x0d, 0x0d, 0x0a, 56, 0, 0, 0,
+		0x4d, 0x3c, 0x2b, 0x1a, 1, 0, 0, 0,
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		3, 0, 5, 0, 'l', 'i', 'n', 'u', 'x', 0, 0, 0,
+		4, 0, 7, 0, 'a', 'p', 'p', ' ', '1', '.', '0', 0,
+		0, 0, 0, 0,
+		56, 0, 0, 0,
+	}, buf.Bytes()[:56])
+}
+
+func TestPcapngInterfaceOptions(t *testing.T) {
+	buf := new(bytes.Buffer)
+	info := InterfaceInfo{Description: "uplink", Speed: 1000000000, Filter: "tcp"}
+	_, err := NewPcapngWriter(buf, DLT{Number: 1}, FirstInterface(info))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{
+		1, 0, 0, 0, 56, 0, 0, 0,
+		1, 0, 0, 0, 0, 0, 4, 0,
+		3, 0, 6, 0, 'u', 'p', 'l', 'i', 'n', 'k', 0, 0,
+		8, 0, 8, 0, 0x00, 0xca, 0x9a, 0x3b, 0, 0, 0, 0,
+		11, 0, 4, 0, 0, 't', 'c', 'p',
+		0, 0, 0, 0,
+		56, 0, 0, 0,
+	}, buf.Bytes()[28:])
+}
+
+func TestSnapshotLength(t *testing.T) {
+	buf := new(bytes.Buffer)
+	pw, err := NewPcapngWriter(buf, DLT{Number: 1}, SnapshotLength(2))
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(2), binary.LittleEndian.Uint32(buf.Bytes()[40:]))
+
+	buf.Reset()
+	assert.NoError(t, pw.WritePacket(time.Unix(0, 0), []byte{1, 2, 3, 4}, 0))
+	assert.Equal(t, []byte{2, 0, 0, 0, 4, 0, 0, 0, 1, 2, 0, 0}, buf.Bytes()[20:32])
+}
+
+func TestWritePacketAllocations(t *testing.T) {
+	data := make([]byte, 1500)
+	ts := time.Now()
+
+	pw, err := NewPcapWriter(io.Discard, DLT{Number: 1})
+	assert.NoError(t, err)
+	assert.Zero(t, testing.AllocsPerRun(100, func() { _ = pw.WritePacket(ts, data, len(data)) }))
+
+	ng, err := NewPcapngWriter(io.Discard, DLT{Number: 1})
+	assert.NoError(t, err)
+	assert.Zero(t, testing.AllocsPerRun(100, func() { _ = ng.WritePacket(ts, data, len(data)) }))
+
+	batch := []Packet{{Timestamp: ts, Data: data}, {Timestamp: ts, Data: data}}
+	assert.Zero(t, testing.AllocsPerRun(100, func() { _ = pw.WritePackets(batch) }))
+	assert.Zero(t, testing.AllocsPerRun(100, func() { _ = ng.WritePackets(batch) }))
+}
+
+// failingWriter fails every write after the first n
+type failingWriter struct {
+	n int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.n == 0 {
+		return 0, io.ErrShortWrite
+	}
+	w.n--
+	return len(p), nil
+}
+
+func TestWriterClose(t *testing.T) {
+	out := new(safeBuffer)
+	bw := NewBufferedWriter(out, 1024, time.Hour)
+	pw, err := NewPcapngWriter(bw, DLT{Number: 1})
+	assert.NoError(t, err)
+	assert.NoError(t, pw.WritePacket(time.Now(), []byte{1, 2, 3}, 3))
+	assert.Empty(t, out.Bytes())
+
+	assert.NoError(t, pw.Close())
+	// section header, interface description, packet and the final statistics with isb_endtime
+	assert.Equal(t, 28+20+36+64, len(out.Bytes()))
+	assert.Equal(t, []byte{3, 0, 8, 0}, out.Bytes()[len(out.Bytes())-20:len(out.Bytes())-16])
+	assert.NoError(t, pw.Close())
+	assert.ErrorIs(t, pw.WritePacket(time.Now(), nil, 0), ErrWriterClosed)
+
+	pcap, err := NewPcapWriter(&failingWriter{n: 1}, DLT{Number: 1})
+	assert.NoError(t, err)
+	assert.ErrorIs(t, pcap.WritePacket(time.Now(), nil, 0), io.ErrShortWrite)
+	assert.ErrorIs(t, pcap.Close(), io.ErrShortWrite)
+	assert.ErrorIs(t, pcap.Close(), io.ErrShortWrite)
+}
+
+func TestAutoFormat(t *testing.T) {
+	pw, err := AutoFormat(io.Discard, DLT{Number: 1}, HostVersion("4.2"))
+	assert.NoError(t, err)
+	assert.IsType(t, &PcapngWriter{}, pw)
+
+	pw, err = AutoFormat(io.Discard, DLT{Number: 1}, HostVersion("2.6"))
+	assert.NoError(t, err)
+	assert.IsType(t, &PcapWriter{}, pw)
+
+	pw, err = AutoFormat(io.Discard, DLT{Number: 1})
+	assert.NoError(t, err)
+	assert.IsType(t, &PcapWriter{}, pw)
+
+	t.Setenv(FormatEnvVar, "pcapng")
+	pw, err = AutoFormat(io.Discard, DLT{Number: 1})
+	assert.NoError(t, err)
+	assert.IsType(t, &PcapngWriter{}, pw)
+}
+
+func TestSupportsPcapng(t *testing.T) {
+	assert.True(t, supportsPcapng("3.0"))
+	assert.True(t, supportsPcapng("10"))
+	assert.False(t, supportsPcapng("2.9"))
+	assert.False(t, supportsPcapng(""))
+}
+
+func TestWritePacketToFile(t *testing.T) {
+	r, w, err := os.Pipe()
+	assert.NoError(t, err)
+	defer r.Close()
+
+	ts := time.Unix(1700000000, 123456789)
+	expected := new(bytes.Buffer)
+	ref, err := NewPcapngWriter(expected, DLT{Number: 1})
+	assert.NoError(t, err)
+	assert.NoError(t, ref.WritePacket(ts, []byte{1, 2, 3, 4, 5}, 60))
+	assert.NoError(t, ref.WritePacketMeta(PacketMeta{Timestamp: ts, Comment: "x"}, []byte{6, 7}))
+
+	result := make(chan []byte)
+	go func() {
+		b, _ := io.ReadAll(r)
+		result <- b
+	}()
+
+	pw, err := NewPcapngWriter(w, DLT{Number: 1})
+	assert.NoError(t, err)
+	assert.NoError(t, pw.WritePacket(ts, []byte{1, 2, 3, 4, 5}, 60))
+	assert.NoError(t, pw.WritePacketMeta(PacketMeta{Timestamp: ts, Comment: "x"}, []byte{6, 7}))
+	assert.NoError(t, w.Close())
+	assert.Equal(t, expected.Bytes(), <-result)
+}
+
+func TestWritePacketPipeClosed(t *testing.T) {
+	r, w, err := os.Pipe()
+	assert.NoError(t, err)
+	defer w.Close()
+	pw, err := NewPcapWriter(w, DLT{Number: 1})
+	assert.NoError(t, err)
+	assert.NoError(t, r.Close())
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	session := &CaptureSession{Context: ctx, cancel: cancel}
+	writer := countingWriter{PacketWriter: pw, session: session}
+	assert.ErrorIs(t, writer.WritePacket(time.Now(), []byte{1}, 1), ErrPipeClosed)
+	assert.ErrorIs(t, context.Cause(ctx), ErrPipeClosed)
+}
+
+func TestWritePackets(t *testing.T) {
+	ts := time.Unix(1700000000, 123456789)
+	packets := []Packet{
+		{Timestamp: ts, Data: []byte{1, 2, 3}, OrigLen: 60},
+		{Timestamp: ts.Add(time.Millisecond), Data: []byte{4, 5, 6, 7, 8}},
+	}
+
+	for name, format := range map[string]PacketWriterFunc{"pcap": PcapFormat, "pcapng": PcapngFormat} {
+		t.Run(name, func(t *testing.T) {
+			expected := new(bytes.Buffer)
+			ref, err := format(expected, DLT{Number: 1})
+			assert.NoError(t, err)
+			for _, p := range packets {
+				assert.NoError(t, ref.WritePacket(p.Timestamp, p.Data, p.OrigLen))
+			}
+
+			r, w, err := os.Pipe()
+			assert.NoError(t, err)
+			defer r.Close()
+			result := make(chan []byte)
+			go func() {
+				b, _ := io.ReadAll(r)
+				result <- b
+			}()
+
+			pw, err := format(w, DLT{Number: 1})
+			assert.NoError(t, err)
+			assert.NoError(t, WritePackets(pw, packets))
+			assert.NoError(t, w.Close())
+			assert.Equal(t, expected.Bytes(), <-result)
+
+			buf := new(bytes.Buffer)
+			pw, err = format(buf, DLT{Number: 1})
+			assert.NoError(t, err)
+			assert.NoError(t, WritePackets(pw, packets))
+			assert.Equal(t, expected.Bytes(), buf.Bytes())
+		})
+	}
+}
+
+func TestConsumeBuffers(t *testing.T) {
+	bufs := consumeBuffers([][]byte{{1, 2}, {3, 4, 5}, {6}}, 3)
+	assert.Equal(t, [][]byte{{4, 5}, {6}}, bufs)
+	assert.Empty(t, consumeBuffers(bufs, 3))
+}
+
+// benchmarkSizes are packet sizes of the writer benchmarks, from small control packets up to jumbo frames
+var benchmarkSizes = []int{64, 512, 1500, 9000, 65535}
+
+func benchmarkWriter(b *testing.B, format PacketWriterFunc) {
+	for _, size := range benchmarkSizes {
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			pw, _ := format(io.Discard, DLT{Number: 1})
+			data := make([]byte, size)
+			ts := time.Now()
+
+			b.ReportAllocs()
+			b.SetBytes(int64(size))
+			for i := 0; i < b.N; i++ {
+				_ = pw.WritePacket(ts, data, len(data))
+			}
+		})
+	}
+}
+
+func benchmarkBatch(b *testing.B, format PacketWriterFunc) {
+	for _, size := range benchmarkSizes {
+		b.Run(strconv.Itoa(size), func(b *testing.B) {
+			pw, _ := format(io.Discard, DLT{Number: 1})
+			batch := make([]Packet, 64)
+			for i := range batch {
+				batch[i] = Packet{Timestamp: time.Now(), Data: make([]byte, size)}
+			}
+
+			b.ReportAllocs()
+			b.SetBytes(int64(size * len(batch)))
+			for i := 0; i < b.N; i++ {
+				_ = WritePackets(pw, batch)
+			}
+		})
+	}
+}
+
+func BenchmarkPcapWriter(b *testing.B) {
+	benchmarkWriter(b, PcapFormat)
+}
+
+func BenchmarkPcapngWriter(b *testing.B) {
+	benchmarkWriter(b, PcapngFormat)
+}
+
+func BenchmarkPcapBatch(b *testing.B) {
+	benchmarkBatch(b, PcapFormat)
+}
+
+func BenchmarkPcapngBatch(b *testing.B) {
+	benchmarkBatch(b, PcapngFormat)
+}
+
+func BenchmarkMeteredWriter(b *testing.B) {
+	benchmarkWriter(b, func(w io.Writer, dlt DLT, opts ...WriterOption) (PacketWriter, error) {
+		pw, err := NewPcapngWriter(w, dlt, opts...)
+		return NewMeteredWriter(pw), err
+	})
+}
@@ -0,0 +1,588 @@
+package extcap
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"sync"
+	"time"
+)
+
+// pcapng block types and option codes, see https://www.ietf.org/archive/id/draft-ietf-opsawg-pcapng-01.html
+const (
+	pcapngSectionHeader        = 0x0a0d0d0a
+	pcapngInterfaceDescription = 0x00000001
+	pcapngNameResolution       = 0x00000004
+	pcapngInterfaceStatistics  = 0x00000005
+	pcapngEnhancedPacket       = 0x00000006
+	pcapngDecryptionSecrets    = 0x0000000a
+	pcapngCustomCopyable       = 0x00000bad
+	pcapngCustomNoCopy         = 0x40000bad
+
+	pcapngByteOrderMagic = 0x1a2b3c4d
+
+	pcapngNrbRecordEnd  = 0
+	pcapngNrbRecordIPv4 = 1
+	pcapngNrbRecordIPv6 = 2
+
+	pcapngOptEndOfOpt      = 0
+	pcapngOptComment       = 1
+	pcapngOptShbHardware   = 2
+	pcapngOptShbOS         = 3
+	pcapngOptShbUserAppl   = 4
+	pcapngOptIfName        = 2
+	pcapngOptIfDescription = 3
+	pcapngOptIfSpeed       = 8
+	pcapngOptIfFilter      = 11
+	pcapngOptTsresol       = 9
+	pcapngOptEpbFlags      = 2
+	pcapngOptEpbDropcount  = 4
+	pcapngOptIsbEndTime    = 3
+	pcapngOptIsbIfRecv     = 4
+	pcapngOptIsbIfDrop     = 5
+)
+
+// PcapngWriter writes packets in the pcapng format.
+// Packets of several sources can be written to one stream, every source is described by its own interface.
+type PcapngWriter struct {
+	w          io.Writer
+	cfg        writerConfig
+	interfaces []*pcapngInterface
+	lastStats  time.Time
+	mu         sync.Mutex
+	// err is the first write error, guarded by mu
+	err    error
+	closed bool
+	// batch, bufs and scratch are reused for blocks, guarded by mu
+	batch   []byte
+	bufs    [][]byte
+	scratch []byte
+}
+
+// pcapngInterface keeps counters of the interface for statistics
+type pcapngInterface struct {
+	dlt      DLT
+	received uint64
+	dropped  uint64
+	// dropped since the last packet, written as epb_dropcount
+	pendingDrops uint64
+}
+
+// NewPcapngWriter writes Section Header Block and Interface Description Block for the link type of dlt to w,
+// the interface has ID 0 and is used by WritePacket. Its options are set with FirstInterface.
+func NewPcapngWriter(w io.Writer, dlt DLT, opts ...WriterOption) (*PcapngWriter, error) {
+	pw := &PcapngWriter{w: w, cfg: newWriterConfig(opts)}
+
+	shb := make([]byte, 16)
+	binary.LittleEndian.PutUint32(shb[0:], pcapngByteOrderMagic)
+	binary.LittleEndian.PutUint16(shb[4:], 1)
+	binary.LittleEndian.PutUint16(shb[6:], 0)
+	// section length is not known in advance
+	binary.LittleEndian.PutUint64(shb[8:], 0xffffffffffffffff)
+
+	var shbOpts []byte
+	if pw.cfg.hardware != "" {
+		shbOpts = appendOption(shbOpts, pcapngOptShbHardware, []byte(pw.cfg.hardware))
+	}
+	if pw.cfg.os != "" {
+		shbOpts = appendOption(shbOpts, pcapngOptShbOS, []byte(pw.cfg.os))
+	}
+	if pw.cfg.application != "" {
+		shbOpts = appendOption(shbOpts, pcapngOptShbUserAppl, []byte(pw.cfg.application))
+	}
+	if shbOpts != nil {
+		shb = append(shb, shbOpts...)
+		shb = appendOption(shb, pcapngOptEndOfOpt, nil)
+	}
+
+	if err := pw.writeBlock(pcapngSectionHeader, shb); err != nil {
+		return nil, err
+	}
+
+	if _, err := pw.AddInterfaceInfo(dlt, pw.cfg.iface); err != nil {
+		return nil, err
+	}
+
+	return pw, nil
+}
+
+// PcapngFormat is PacketWriterFunc for App.NewPacketWriter creating PcapngWriter
+func PcapngFormat(w io.Writer, dlt DLT, opts ...WriterOption) (PacketWriter, error) {
+	return NewPcapngWriter(w, dlt, opts...)
+}
+
+// InterfaceInfo describes capture interface in Interface Description Block,
+// Wireshark shows it in Statistics > Capture File Properties. Empty fields are not written.
+type InterfaceInfo struct {
+	// Name is the name of the interface, e.g. eth0
+	Name string
+	// Description is human-readable description of the interface
+	Description string
+	// Speed is the link speed in bits per second
+	Speed uint64
+	// Filter is the capture filter applied to the interface
+	Filter string
+}
+
+// AddInterface writes Interface Description Block for the link type of dlt and returns ID of the interface.
+// Name is written as if_name option unless it is empty.
+func (pw *PcapngWriter) AddInterface(dlt DLT, name string) (int, error) {
+	return pw.AddInterfaceInfo(dlt, InterfaceInfo{Name: name})
+}
+
+// AddInterfaceInfo writes Interface Description Block for the link type of dlt with options of info
+// and returns ID of the interface
+func (pw *PcapngWriter) AddInterfaceInfo(dlt DLT, info InterfaceInfo) (int, error) {
+	idb := make([]byte, 8)
+	binary.LittleEndian.PutUint16(idb[0:], uint16(dlt.Number))
+	binary.LittleEndian.PutUint32(idb[4:], uint32(pw.cfg.snaplen))
+
+	var opts []byte
+	if info.Name != "" {
+		opts = appendOption(opts, pcapngOptIfName, []byte(info.Name))
+	}
+	if info.Description != "" {
+		opts = appendOption(opts, pcapngOptIfDescription, []byte(info.Description))
+	}
+	if info.Speed != 0 {
+		opts = appendOption(opts, pcapngOptIfSpeed, binary.LittleEndian.AppendUint64(nil, info.Speed))
+	}
+	if info.Filter != "" {
+		// the first byte is the filter type, 0 is libpcap filter string
+		opts = appendOption(opts, pcapngOptIfFilter, append([]byte{0}, info.Filter...))
+	}
+	if pw.cfg.nanosecond {
+		// if_tsresol is power of 10 of the resolution
+		opts = appendOption(opts, pcapngOptTsresol, []byte{9})
+	}
+	if opts != nil {
+		idb = append(idb, opts...)
+		idb = appendOption(idb, pcapngOptEndOfOpt, nil)
+	}
+
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+
+	// interface IDs are assigned in the order of the blocks in the stream
+	if err := pw.writeBlockLocked(pcapngInterfaceDescription, idb); err != nil {
+		return 0, err
+	}
+	pw.interfaces = append(pw.interfaces, &pcapngInterface{dlt: dlt})
+
+	return len(pw.interfaces) - 1, nil
+}
+
+// WritePacket writes Enhanced Packet Block for interface 0, data longer than snaplen is truncated
+// and origLen shorter than data is corrected. It is safe to call from multiple goroutines,
+// every block is written with a single Write or writev.
+func (pw *PcapngWriter) WritePacket(ts time.Time, data []byte, origLen int) error {
+	return pw.WriteInterfacePacket(0, ts, data, origLen)
+}
+
+// WriteInterfacePacket writes Enhanced Packet Block for the interface returned by AddInterface
+func (pw *PcapngWriter) WriteInterfacePacket(id int, ts time.Time, data []byte, origLen int) error {
+	return pw.writeEnhancedPacket(id, ts, data, origLen, nil)
+}
+
+// WritePacketComment writes Enhanced Packet Block for interface 0 with the comment
+func (pw *PcapngWriter) WritePacketComment(ts time.Time, data []byte, origLen int, comment string) error {
+	return pw.WritePacketMeta(PacketMeta{Timestamp: ts, OrigLen: origLen, Comment: comment}, data)
+}
+
+// PacketFlags are the epb_flags of Enhanced Packet Block, direction, reception type and link-layer errors
+type PacketFlags uint32
+
+const (
+	// FlagInbound marks packet received by the interface
+	FlagInbound PacketFlags = 1
+	// FlagOutbound marks packet sent by the interface
+	FlagOutbound PacketFlags = 2
+
+	// FlagUnicast, FlagMulticast, FlagBroadcast and FlagPromiscuous are the reception types
+	FlagUnicast     PacketFlags = 1 << 2
+	FlagMulticast   PacketFlags = 2 << 2
+	FlagBroadcast   PacketFlags = 3 << 2
+	FlagPromiscuous PacketFlags = 4 << 2
+
+	// Link-layer errors
+	FlagSymbolError     PacketFlags = 1 << 24
+	FlagPreambleError   PacketFlags = 1 << 25
+	FlagFrameDelimError PacketFlags = 1 << 26
+	FlagUnalignedError  PacketFlags = 1 << 27
+	FlagWrongIFGError   PacketFlags = 1 << 28
+	FlagPacketTooShort  PacketFlags = 1 << 29
+	FlagPacketTooLong   PacketFlags = 1 << 30
+	FlagCRCError        PacketFlags = 1 << 31
+)
+
+// PacketMeta describes packet written with WritePacketMeta
+type PacketMeta struct {
+	// Interface is ID returned by AddInterface, 0 for the interface of NewPcapngWriter
+	Interface int
+	// Timestamp is the time the packet was captured
+	Timestamp time.Time
+	// OrigLen is the length of the packet on the wire
+	OrigLen int
+	// Flags are direction, reception type and errors of the packet, not written if zero
+	Flags PacketFlags
+	// Comment is shown by Wireshark in the packet details, not written if empty
+	Comment string
+}
+
+// WritePacketMeta writes Enhanced Packet Block with options described by meta
+func (pw *PcapngWriter) WritePacketMeta(meta PacketMeta, data []byte) error {
+	var opts []byte
+	if meta.Flags != 0 {
+		opts = appendOption(opts, pcapngOptEpbFlags, binary.LittleEndian.AppendUint32(nil, uint32(meta.Flags)))
+	}
+	if meta.Comment != "" {
+		opts = appendOption(opts, pcapngOptComment, []byte(meta.Comment))
+	}
+
+	return pw.writeEnhancedPacket(meta.Interface, meta.Timestamp, data, meta.OrigLen, opts)
+}
+
+// writeEnhancedPacket writes Enhanced Packet Block with options, which must be padded and not terminated
+func (pw *PcapngWriter) writeEnhancedPacket(id int, ts time.Time, data []byte, origLen int, opts []byte) error {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+
+	if err := pw.checkLocked(); err != nil {
+		return err
+	}
+	if id < 0 || id >= len(pw.interfaces) {
+		return fmt.Errorf("%w: %d", ErrUnknownInterface, id)
+	}
+
+	// header and trailer are built in the reused buffer, so writing packets does not allocate
+	data, origLen = pw.cfg.truncate(data, origLen)
+	b := pw.appendEnhancedPacket(pw.batch[:0], id, ts, len(data), origLen, opts)
+	pw.batch = b
+
+	var err error
+	if pw.scratch, err = writeRecord(pw.w, pw.scratch, b[:28], data, b[28:]); err != nil {
+		pw.err = err
+		return err
+	}
+	return pw.maybeWriteStatisticsLocked(ts)
+}
+
+// WritePackets writes Enhanced Packet Blocks of all packets for interface 0 with a single Write or writev,
+// see WritePacket
+func (pw *PcapngWriter) WritePackets(packets []Packet) error {
+	if len(packets) == 0 {
+		return nil
+	}
+
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+
+	if err := pw.checkLocked(); err != nil {
+		return err
+	}
+
+	// b has the capacity for header and trailer with epb_dropcount of every packet,
+	// so the earlier blocks are not moved by reallocation
+	b := slices.Grow(pw.batch[:0], (28+3+16+4)*len(packets))
+	bufs := pw.bufs[:0]
+	for _, p := range packets {
+		data, origLen := pw.cfg.truncate(p.Data, p.OrigLen)
+		start := len(b)
+		b = pw.appendEnhancedPacket(b, 0, p.Timestamp, len(data), origLen, nil)
+		bufs = append(bufs, b[start:start+28], data, b[start+28:])
+	}
+	pw.batch, pw.bufs = b, bufs
+
+	var err error
+	if pw.scratch, err = writeBuffers(pw.w, pw.scratch, bufs); err != nil {
+		pw.err = err
+		return err
+	}
+	return pw.maybeWriteStatisticsLocked(packets[len(packets)-1].Timestamp)
+}
+
+// appendEnhancedPacket appends Enhanced Packet Block without the packet data to b, the 28 bytes of the header
+// are followed by the trailer. It counts the packet as received by the interface.
+func (pw *PcapngWriter) appendEnhancedPacket(b []byte, id int, ts time.Time, length, origLen int, opts []byte) []byte {
+	iface := pw.interfaces[id]
+
+	ts = pw.cfg.timestamp(ts)
+	units := uint64(ts.UnixMicro())
+	if pw.cfg.nanosecond {
+		units = uint64(ts.UnixNano())
+	}
+
+	start := len(b)
+	b = append(b, make([]byte, 28)...)
+	b = append(b, padding[:pad4(length)-length]...)
+	if len(opts) > 0 || iface.pendingDrops > 0 {
+		b = append(b, opts...)
+		if iface.pendingDrops > 0 {
+			var drops [8]byte
+			binary.LittleEndian.PutUint64(drops[:], iface.pendingDrops)
+			b = appendOption(b, pcapngOptEpbDropcount, drops[:])
+		}
+		b = appendOption(b, pcapngOptEndOfOpt, nil)
+	}
+	total := uint32(len(b) - start + length + 4)
+	b = binary.LittleEndian.AppendUint32(b, total)
+
+	header := b[start:]
+	binary.LittleEndian.PutUint32(header[0:], pcapngEnhancedPacket)
+	binary.LittleEndian.PutUint32(header[4:], total)
+	binary.LittleEndian.PutUint32(header[8:], uint32(id))
+	binary.LittleEndian.PutUint32(header[12:], uint32(units>>32))
+	binary.LittleEndian.PutUint32(header[16:], uint32(units))
+	binary.LittleEndian.PutUint32(header[20:], uint32(length))
+	binary.LittleEndian.PutUint32(header[24:], uint32(origLen))
+
+	iface.received++
+	iface.pendingDrops = 0
+	return b
+}
+
+// maybeWriteStatisticsLocked writes Interface Statistics Blocks if the statistics interval has passed
+func (pw *PcapngWriter) maybeWriteStatisticsLocked(ts time.Time) error {
+	ts = pw.cfg.timestamp(ts)
+	if pw.cfg.statsInterval > 0 && ts.Sub(pw.lastStats) >= pw.cfg.statsInterval {
+		return pw.writeStatisticsLocked(ts, false)
+	}
+	return nil
+}
+
+// ReportDropped counts n packets of the interface dropped by the source or because of backpressure.
+// The drops are written as epb_dropcount of the next packet and in Interface Statistics Blocks.
+func (pw *PcapngWriter) ReportDropped(id int, n uint64) error {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+
+	if id < 0 || id >= len(pw.interfaces) {
+		return fmt.Errorf("%w: %d", ErrUnknownInterface, id)
+	}
+	pw.interfaces[id].dropped += n
+	pw.interfaces[id].pendingDrops += n
+	return nil
+}
+
+// WriteStatistics writes Interface Statistics Block for every interface, e.g. at the end of the capture
+func (pw *PcapngWriter) WriteStatistics() error {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+
+	if err := pw.checkLocked(); err != nil {
+		return err
+	}
+	return pw.writeStatisticsLocked(time.Now(), false)
+}
+
+// Flush writes data buffered by the underlying writer, e.g. BufferedWriter, to the fifo
+func (pw *PcapngWriter) Flush() error {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+
+	if err := pw.checkLocked(); err != nil {
+		return err
+	}
+	return pw.flushLocked()
+}
+
+// Close writes final Interface Statistics Blocks with the end time of the capture and flushes the underlying writer,
+// which is not closed. It returns the first write error of the writer, calling it again returns the same result.
+func (pw *PcapngWriter) Close() error {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+
+	if pw.closed {
+		return pw.err
+	}
+	if pw.err == nil {
+		if err := pw.writeStatisticsLocked(time.Now(), true); err == nil {
+			_ = pw.flushLocked()
+		}
+	}
+	pw.closed = true
+	return pw.err
+}
+
+// checkLocked returns error if writing is not possible anymore
+func (pw *PcapngWriter) checkLocked() error {
+	if pw.err != nil {
+		return pw.err
+	}
+	if pw.closed {
+		return ErrWriterClosed
+	}
+	return nil
+}
+
+func (pw *PcapngWriter) flushLocked() error {
+	if f, ok := pw.w.(flusher); ok {
+		if err := f.Flush(); err != nil {
+			pw.err = pipeError(err)
+			return pw.err
+		}
+	}
+	return nil
+}
+
+func (pw *PcapngWriter) writeStatisticsLocked(ts time.Time, final bool) error {
+	pw.lastStats = ts
+	units := uint64(ts.UnixMicro())
+	if pw.cfg.nanosecond {
+		units = uint64(ts.UnixNano())
+	}
+
+	for id, iface := range pw.interfaces {
+		isb := make([]byte, 12)
+		binary.LittleEndian.PutUint32(isb[0:], uint32(id))
+		binary.LittleEndian.PutUint32(isb[4:], uint32(units>>32))
+		binary.LittleEndian.PutUint32(isb[8:], uint32(units))
+		// packets dropped by the source never reached the writer, so they are counted as received too
+		isb = appendOption(isb, pcapngOptIsbIfRecv, binary.LittleEndian.AppendUint64(nil, iface.received+iface.dropped))
+		isb = appendOption(isb, pcapngOptIsbIfDrop, binary.LittleEndian.AppendUint64(nil, iface.dropped))
+		if final {
+			end := binary.LittleEndian.AppendUint32(nil, uint32(units>>32))
+			isb = appendOption(isb, pcapngOptIsbEndTime, binary.LittleEndian.AppendUint32(end, uint32(units)))
+		}
+		isb = appendOption(isb, pcapngOptEndOfOpt, nil)
+
+		if err := pw.writeBlockLocked(pcapngInterfaceStatistics, isb); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NameRecord maps IP address to the names Wireshark shows instead of it
+type NameRecord struct {
+	IP    net.IP
+	Names []string
+}
+
+// WriteNameResolution writes Name Resolution Block with the records, so Wireshark resolves the addresses without lookups
+func (pw *PcapngWriter) WriteNameResolution(records ...NameRecord) error {
+	var nrb []byte
+	for _, rec := range records {
+		recordType := uint16(pcapngNrbRecordIPv6)
+		addr := rec.IP.To16()
+		if ip4 := rec.IP.To4(); ip4 != nil {
+			recordType = pcapngNrbRecordIPv4
+			addr = ip4
+		}
+		if addr == nil {
+			return fmt.Errorf("%w: invalid IP address %v", ErrValueInvalid, rec.IP)
+		}
+
+		value := append([]byte(nil), addr...)
+		for _, name := range rec.Names {
+			value = append(append(value, name...), 0)
+		}
+		nrb = appendOption(nrb, recordType, value)
+	}
+	nrb = appendOption(nrb, pcapngNrbRecordEnd, nil)
+
+	return pw.writeBlock(pcapngNameResolution, nrb)
+}
+
+// SecretsType is the format of the secrets in Decryption Secrets Block
+type SecretsType uint32
+
+const (
+	// SecretsTLSKeyLog is NSS key log format used by SSLKEYLOGFILE
+	SecretsTLSKeyLog SecretsType = 0x544c534b
+	// SecretsSSHKeyLog is the SSH key log format
+	SecretsSSHKeyLog SecretsType = 0x5353484b
+	// SecretsWireGuardKeyLog is the WireGuard key log format
+	SecretsWireGuardKeyLog SecretsType = 0x57474b4c
+	// SecretsZigBeeNWKKey is the ZigBee network key
+	SecretsZigBeeNWKKey SecretsType = 0x5a4e574b
+	// SecretsZigBeeAPSKey is the ZigBee application support key
+	SecretsZigBeeAPSKey SecretsType = 0x5a415053
+)
+
+// WriteDecryptionSecrets writes Decryption Secrets Block, so Wireshark decrypts traffic of the following packets
+func (pw *PcapngWriter) WriteDecryptionSecrets(secretsType SecretsType, data []byte) error {
+	dsb := make([]byte, 8, 8+pad4(len(data)))
+	binary.LittleEndian.PutUint32(dsb[0:], uint32(secretsType))
+	binary.LittleEndian.PutUint32(dsb[4:], uint32(len(data)))
+	dsb = append(dsb, data...)
+	dsb = append(dsb, padding[:pad4(len(data))-len(data)]...)
+
+	return pw.writeBlock(pcapngDecryptionSecrets, dsb)
+}
+
+// WriteBlock writes block of any type, body is padded to 32 bits. It is an escape hatch for blocks
+// the writer does not support, the caller is responsible for the body to be valid for the block type.
+func (pw *PcapngWriter) WriteBlock(blockType uint32, body []byte) error {
+	padded := make([]byte, pad4(len(body)))
+	copy(padded, body)
+
+	return pw.writeBlock(blockType, padded)
+}
+
+// WriteCustomBlock writes Custom Block with vendor data, pen is the IANA Private Enterprise Number of the vendor.
+// Copyable blocks may be kept by tools rewriting the file, the others are dropped by them.
+func (pw *PcapngWriter) WriteCustomBlock(pen uint32, data []byte, copyable bool) error {
+	blockType := uint32(pcapngCustomNoCopy)
+	if copyable {
+		blockType = pcapngCustomCopyable
+	}
+
+	body := binary.LittleEndian.AppendUint32(make([]byte, 0, 4+len(data)), pen)
+	return pw.WriteBlock(blockType, append(body, data...))
+}
+
+// writeBlock frames body, which must be padded to 32 bits, as pcapng block and writes it
+func (pw *PcapngWriter) writeBlock(blockType uint32, body []byte) error {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+
+	return pw.writeBlockLocked(blockType, body)
+}
+
+// writeBlockLocked is writeBlock for callers holding the lock
+func (pw *PcapngWriter) writeBlockLocked(blockType uint32, body []byte) error {
+	if err := pw.checkLocked(); err != nil {
+		return err
+	}
+	return pw.endBlock(append(pw.beginBlock(blockType), body...))
+}
+
+// beginBlock starts block in the scratch buffer, the caller must hold the lock and append the body
+func (pw *PcapngWriter) beginBlock(blockType uint32) []byte {
+	b := binary.LittleEndian.AppendUint32(pw.scratch[:0], blockType)
+	// total length is set by endBlock
+	return append(b, 0, 0, 0, 0)
+}
+
+// endBlock sets total length of the block started by beginBlock and writes it
+func (pw *PcapngWriter) endBlock(b []byte) error {
+	length := uint32(len(b) + 4)
+	binary.LittleEndian.PutUint32(b[4:], length)
+	b = binary.LittleEndian.AppendUint32(b, length)
+	pw.scratch = b
+
+	if _, err := pw.w.Write(b); err != nil {
+		pw.err = pipeError(err)
+		return pw.err
+	}
+	return nil
+}
+
+// appendOption appends option padded to 32 bits to the block body, name resolution records have the same layout
+func appendOption(body []byte, code uint16, value []byte) []byte {
+	body = binary.LittleEndian.AppendUint16(body, code)
+	body = binary.LittleEndian.AppendUint16(body, uint16(len(value)))
+	body = append(body, value...)
+	return append(body, padding[:pad4(len(value))-len(value)]...)
+}
+
+// padding is appended to align data to 32 bits
+var padding [3]byte
+
+// pad4 rounds n up to multiple of 4
+func pad4(n int) int {
+	return (n + 3) &^ 3
+}
@@ -0,0 +1,55 @@
+package extcap
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// DefaultPipeOpenTimeout is how long the fifo open is retried by default
+const DefaultPipeOpenTimeout = 10 * time.Second
+
+const (
+	pipeOpenMinBackoff = 10 * time.Millisecond
+	pipeOpenMaxBackoff = 500 * time.Millisecond
+)
+
+// StdoutFifo is the --fifo value making the capture stream go to stdout, e.g. to pipe it into wireshark -k -i -
+const StdoutFifo = "-"
+
+// openPipe opens the fifo for writing, the open is retried with backoff while Wireshark has not opened
+// the reading end yet, so starting the capture does not fail nor block forever.
+// If create is set, missing fifo is created first.
+func openPipe(name string, timeout time.Duration, create bool) (io.WriteCloser, error) {
+	if name == StdoutFifo {
+		return os.Stdout, nil
+	}
+	if create {
+		if err := createFifo(name); err != nil {
+			return nil, fmt.Errorf("unable to create pipe: %w", err)
+		}
+	}
+
+	if timeout <= 0 {
+		timeout = DefaultPipeOpenTimeout
+	}
+	deadline := time.Now().Add(timeout)
+
+	backoff := pipeOpenMinBackoff
+	for {
+		pipe, err := openFifo(name)
+		if err == nil {
+			return pipe, nil
+		}
+		if !retryOpen(err) {
+			return nil, fmt.Errorf("unable to open pipe: %w", err)
+		}
+		if time.Now().Add(backoff).After(deadline) {
+			return nil, fmt.Errorf("%w %s after %v: %w", ErrPipeOpenTimeout, name, timeout, err)
+		}
+
+		time.Sleep(backoff)
+		backoff = min(backoff*2, pipeOpenMaxBackoff)
+	}
+}
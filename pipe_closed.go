@@ -0,0 +1,20 @@
+package extcap
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// pipeError wraps err with ErrPipeClosed if it is caused by the reader of the fifo closing it
+func pipeError(err error) error {
+	if err == nil || errors.Is(err, ErrPipeClosed) {
+		return err
+	}
+	for _, closed := range append(pipeClosedErrors, io.ErrClosedPipe) {
+		if errors.Is(err, closed) {
+			return fmt.Errorf("%w: %w", ErrPipeClosed, err)
+		}
+	}
+	return err
+}
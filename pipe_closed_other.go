@@ -0,0 +1,8 @@
+//go:build !windows
+
+package extcap
+
+import "syscall"
+
+// pipeClosedErrors are returned by writes to a fifo closed by the reader
+var pipeClosedErrors = []error{syscall.EPIPE}
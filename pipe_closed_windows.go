@@ -0,0 +1,8 @@
+//go:build windows
+
+package extcap
+
+import "golang.org/x/sys/windows"
+
+// pipeClosedErrors are returned by writes to a named pipe closed by the reader
+var pipeClosedErrors = []error{windows.ERROR_BROKEN_PIPE, windows.ERROR_NO_DATA}
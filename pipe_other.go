@@ -0,0 +1,32 @@
+//go:build !windows
+
+package extcap
+
+import (
+	"errors"
+	"io"
+	"os"
+	"syscall"
+)
+
+// openFifo opens the fifo without blocking, it fails with ENXIO if there is no reader yet
+func openFifo(name string) (io.WriteCloser, error) {
+	f, err := os.OpenFile(name, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// retryOpen reports if the fifo open failed because the reader is not ready yet
+func retryOpen(err error) bool {
+	return errors.Is(err, syscall.ENXIO) || errors.Is(err, os.ErrNotExist)
+}
+
+// createFifo creates the fifo unless the path exists
+func createFifo(name string) error {
+	if err := syscall.Mkfifo(name, 0o600); err != nil && !errors.Is(err, os.ErrExist) {
+		return err
+	}
+	return nil
+}
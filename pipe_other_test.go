@@ -0,0 +1,52 @@
+//go:build !windows
+
+package extcap
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenPipeWaitsForReader(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "fifo")
+	assert.NoError(t, syscall.Mkfifo(name, 0o600))
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		r, err := os.Open(name)
+		if err == nil {
+			defer r.Close()
+			_, _ = r.Read(make([]byte, 3))
+		}
+	}()
+
+	pipe, err := openPipe(name, time.Second, false)
+	assert.NoError(t, err)
+	_, err = pipe.Write([]byte("abc"))
+	assert.NoError(t, err)
+	assert.NoError(t, pipe.Close())
+}
+
+func TestOpenPipeTimeout(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "fifo")
+	assert.NoError(t, syscall.Mkfifo(name, 0o600))
+
+	_, err := openPipe(name, 50*time.Millisecond, false)
+	assert.ErrorIs(t, err, ErrPipeOpenTimeout)
+	assert.ErrorIs(t, err, syscall.ENXIO)
+}
+
+func TestOpenPipeCreate(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "fifo")
+
+	_, err := openPipe(name, 50*time.Millisecond, true)
+	assert.ErrorIs(t, err, ErrPipeOpenTimeout)
+	info, err := os.Stat(name)
+	assert.NoError(t, err)
+	assert.Equal(t, os.ModeNamedPipe, info.Mode().Type())
+}
@@ -0,0 +1,15 @@
+package extcap
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenPipeStdout(t *testing.T) {
+	pipe, err := openPipe(StdoutFifo, time.Second, false)
+	assert.NoError(t, err)
+	assert.Equal(t, os.Stdout, pipe)
+}
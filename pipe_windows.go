@@ -0,0 +1,131 @@
+//go:build windows
+
+package extcap
+
+import (
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sys/windows"
+)
+
+// namedPipePrefix is the prefix of the named pipe paths Wireshark passes with --fifo
+const namedPipePrefix = `\\.\pipe\`
+
+// openFifo opens the named pipe created by Wireshark, other paths are opened as regular files
+func openFifo(name string) (io.WriteCloser, error) {
+	if !strings.HasPrefix(strings.ToLower(name), namedPipePrefix) {
+		f, err := os.OpenFile(name, os.O_WRONLY, 0)
+		if err != nil {
+			return nil, err
+		}
+		return f, nil
+	}
+
+	p, err := openNamedPipe(name)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// retryOpen reports if the pipe open failed because the pipe is not created or is busy yet
+func retryOpen(err error) bool {
+	return errors.Is(err, os.ErrNotExist) || errors.Is(err, windows.ERROR_PIPE_BUSY)
+}
+
+// createFifo is not supported, named pipes are created by the reader
+func createFifo(string) error {
+	return errors.ErrUnsupported
+}
+
+// namedPipe writes to the named pipe with overlapped I/O, so Close cancels a pending write
+type namedPipe struct {
+	name   string
+	handle windows.Handle
+	event  windows.Handle
+
+	// mu serializes writes, closeMu guards closed and the handles against Close during write
+	mu      sync.Mutex
+	closeMu sync.RWMutex
+	closed  bool
+	closing atomic.Bool
+}
+
+func openNamedPipe(name string) (*namedPipe, error) {
+	path, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return nil, err
+	}
+
+	handle, err := windows.CreateFile(path, windows.GENERIC_WRITE, 0, nil, windows.OPEN_EXISTING, windows.FILE_FLAG_OVERLAPPED, 0)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	event, err := windows.CreateEvent(nil, 1, 0, nil)
+	if err != nil {
+		_ = windows.CloseHandle(handle)
+		return nil, err
+	}
+
+	return &namedPipe{name: name, handle: handle, event: event}, nil
+}
+
+// Write writes p to the pipe, it waits until the whole p is written
+func (p *namedPipe) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+	if p.closed {
+		return 0, os.ErrClosed
+	}
+
+	written := 0
+	for written < len(b) {
+		// Close cancels only the I/O already started, do not start a new one after it
+		if p.closing.Load() {
+			return written, os.ErrClosed
+		}
+		var n uint32
+		ov := windows.Overlapped{HEvent: p.event}
+		err := windows.WriteFile(p.handle, b[written:], &n, &ov)
+		if errors.Is(err, windows.ERROR_IO_PENDING) {
+			err = windows.GetOverlappedResult(p.handle, &ov, &n, true)
+		}
+		written += int(n)
+		if errors.Is(err, windows.ERROR_OPERATION_ABORTED) {
+			return written, os.ErrClosed
+		}
+		if err != nil {
+			return written, &os.PathError{Op: "write", Path: p.name, Err: err}
+		}
+	}
+	return written, nil
+}
+
+// Close cancels pending write and closes the pipe
+func (p *namedPipe) Close() error {
+	if !p.closing.CompareAndSwap(false, true) {
+		return os.ErrClosed
+	}
+	// pending write holds the read lock, cancel it until the write returns. A write which checked closing
+	// just before it was set may start after the first cancel.
+	for {
+		_ = windows.CancelIoEx(p.handle, nil)
+		if p.closeMu.TryLock() {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	defer p.closeMu.Unlock()
+	p.closed = true
+
+	return errors.Join(windows.CloseHandle(p.handle), windows.CloseHandle(p.event))
+}
@@ -0,0 +1,162 @@
+/*
+Package presets provides commonly needed extcap config options, pre-configured with types, tooltips and numbers.
+
+Every function returns a new option, so it can be customized further, e.g.
+
+	presets.RemotePort().Default(2222)
+
+Presets have explicit numbers starting from 100, so they never collide with options numbered by their position.
+*/
+package presets
+
+import (
+	"time"
+
+	"github.com/lion7/extcap"
+)
+
+// Names of the preset options, to be used with extcap.Options accessors
+const (
+	SnaplenName        = "snaplen"
+	PromiscuousName    = "promiscuous"
+	CountName          = "count"
+	DurationName       = "capture-duration"
+	RemoteHostName     = "remote-host"
+	RemotePortName     = "remote-port"
+	RemoteUsernameName = "remote-username"
+	RemotePasswordName = "remote-password"
+	SizeName           = "capture-size"
+	SamplingName       = "sampling"
+	PayloadName        = "payload"
+)
+
+// presetsNumberOffset is the number of the first preset option
+const presetsNumberOffset = 100
+
+// Snaplen returns option limiting number of bytes captured from each packet
+func Snaplen() *extcap.ConfigIntegerOpt {
+	return extcap.NewConfigIntegerOpt(SnaplenName, "Snapshot length").
+		Tooltip("Maximum number of bytes captured from each packet").
+		Range(1, extcap.DefaultSnaplen).
+		Default(extcap.DefaultSnaplen).
+		Number(presetsNumberOffset)
+}
+
+// SnaplenWriterOption returns writer option applying value of the Snaplen option to the built-in packet writers
+func SnaplenWriterOption(opts extcap.Options) extcap.WriterOption {
+	return extcap.SnapshotLength(opts.Int(SnaplenName))
+}
+
+// Promiscuous returns option enabling promiscuous mode of the capture interface
+func Promiscuous() *extcap.ConfigBoolOpt {
+	return extcap.NewConfigBoolOpt(PromiscuousName, "Promiscuous mode").
+		Tooltip("Capture packets not addressed to the interface").
+		Default(true).
+		Number(presetsNumberOffset + 1)
+}
+
+// Count returns option stopping the capture after given number of packets, 0 means no limit
+func Count() *extcap.ConfigLongOpt {
+	return extcap.NewConfigLongOpt(CountName, "Packet count").
+		Tooltip("Stop capture after given number of packets, 0 means no limit").
+		Min(0).
+		Default(0).
+		Number(presetsNumberOffset + 2)
+}
+
+// Duration returns option stopping the capture after given time, 0 means no limit
+func Duration() *extcap.ConfigDurationOpt {
+	return extcap.NewConfigDurationOpt(DurationName, "Capture duration").
+		Tooltip("Stop capture after given time (e.g. 30s, 5m), 0 means no limit").
+		Default(time.Duration(0)).
+		Number(presetsNumberOffset + 3)
+}
+
+// Size returns option stopping the capture after given number of bytes of packet data, 0 means no limit
+func Size() *extcap.ConfigLongOpt {
+	return extcap.NewConfigLongOpt(SizeName, "Capture size").
+		Tooltip("Stop capture after given number of bytes, 0 means no limit").
+		Min(0).
+		Default(0).
+		Number(presetsNumberOffset + 8)
+}
+
+// Autostop returns autostop conditions for App.Autostop from values of the Count, Duration and Size options
+func Autostop(opts extcap.Options) extcap.Autostop {
+	return extcap.Autostop{
+		Duration: opts.Duration(DurationName),
+		Packets:  uint64(max(opts.Int64(CountName), 0)),
+		Bytes:    uint64(max(opts.Int64(SizeName), 0)),
+	}
+}
+
+// Sampling returns option keeping only every N-th packet, 1 keeps all packets
+func Sampling() *extcap.ConfigIntegerOpt {
+	return extcap.NewConfigIntegerOpt(SamplingName, "Sampling").
+		Tooltip("Capture only every N-th packet, 1 captures all packets").
+		Min(1).
+		Default(1).
+		Number(presetsNumberOffset + 9)
+}
+
+// Sampler returns sampler for the value of the Sampling option, nil if all packets are captured
+func Sampler(opts extcap.Options) *extcap.Sampler {
+	if n := opts.Int(SamplingName); n > 1 {
+		return extcap.NewSampler(uint64(n))
+	}
+	return nil
+}
+
+// Payload returns option selecting what is done with packet payloads, see extcap.PayloadPolicy
+func Payload() *extcap.ConfigSelectorOpt {
+	return extcap.NewConfigSelectorOpt(PayloadName, "Payload").
+		Tooltip("Keep, zero or remove packet payloads after the transport header").
+		Values(
+			extcap.OptValue{Value: string(extcap.PayloadKeep), Display: "Keep", Default: true},
+			extcap.OptValue{Value: string(extcap.PayloadZero), Display: "Zero"},
+			extcap.OptValue{Value: string(extcap.PayloadTruncate), Display: "Truncate"},
+		).
+		Number(presetsNumberOffset + 10)
+}
+
+// PayloadTransform returns transform for the value of the Payload option, nil if payloads are kept
+func PayloadTransform(opts extcap.Options, dlt extcap.DLT) (extcap.Transform, error) {
+	policy := extcap.PayloadPolicy(opts.String(PayloadName))
+	if policy == "" || policy == extcap.PayloadKeep {
+		return nil, nil
+	}
+	return extcap.MaskPayload(dlt, policy)
+}
+
+// RemoteHost returns option with address of the remote host
+func RemoteHost() *extcap.ConfigStringOpt {
+	return extcap.NewConfigStringOpt(RemoteHostName, "Remote host").
+		Tooltip("Hostname or IP address of the remote host").
+		Placeholder("hostname or IP").
+		MustSet().
+		Number(presetsNumberOffset + 4)
+}
+
+// RemotePort returns option with port of the remote host
+func RemotePort() *extcap.ConfigUnsignedOpt {
+	return extcap.NewConfigUnsignedOpt(RemotePortName, "Remote port").
+		Tooltip("Port of the remote host").
+		Range(1, 65535).
+		Default(22).
+		Number(presetsNumberOffset + 5)
+}
+
+// RemoteUsername returns option with username used to log in to the remote host
+func RemoteUsername() *extcap.ConfigStringOpt {
+	return extcap.NewConfigStringOpt(RemoteUsernameName, "Remote username").
+		Tooltip("Username used to log in to the remote host").
+		Number(presetsNumberOffset + 6)
+}
+
+// RemotePassword returns option with password used to log in to the remote host, it requires RemoteUsername
+func RemotePassword() *extcap.ConfigPasswordOpt {
+	return extcap.NewConfigPasswordOpt(RemotePasswordName, "Remote password").
+		Tooltip("Password used to log in to the remote host").
+		Requires(RemoteUsernameName).
+		Number(presetsNumberOffset + 7)
+}
@@ -0,0 +1,56 @@
+package presets
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lion7/extcap"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPresetNumbers(t *testing.T) {
+	opts := []extcap.ConfigOption{
+		extcap.NewConfigStringOpt("message", "Message"),
+		Snaplen(), Promiscuous(), Count(), Duration(),
+		RemoteHost(), RemotePort(), RemoteUsername(), RemotePassword(), Size(), Sampling(), Payload(),
+	}
+
+	assert.NoError(t, extcap.VerifyOptionNumbers(opts, map[string]int{
+		"message":          0,
+		SnaplenName:        100,
+		PromiscuousName:    101,
+		CountName:          102,
+		DurationName:       103,
+		RemoteHostName:     104,
+		RemotePortName:     105,
+		RemoteUsernameName: 106,
+		RemotePasswordName: 107,
+		SizeName:           108,
+		SamplingName:       109,
+		PayloadName:        110,
+	}))
+}
+
+func TestAutostop(t *testing.T) {
+	opts := extcap.Options{CountName: int64(10), DurationName: time.Minute, SizeName: int64(0)}
+	assert.Equal(t, extcap.Autostop{Duration: time.Minute, Packets: 10}, Autostop(opts))
+}
+
+func TestSampler(t *testing.T) {
+	assert.Nil(t, Sampler(extcap.Options{SamplingName: 1}))
+	assert.NotNil(t, Sampler(extcap.Options{SamplingName: 10}))
+}
+
+func TestPayloadTransform(t *testing.T) {
+	dlt := extcap.DLT{Number: 1}
+	transform, err := PayloadTransform(extcap.Options{PayloadName: "keep"}, dlt)
+	assert.NoError(t, err)
+	assert.Nil(t, transform)
+
+	transform, err = PayloadTransform(extcap.Options{PayloadName: "truncate"}, dlt)
+	assert.NoError(t, err)
+	assert.NotNil(t, transform)
+
+	_, err = PayloadTransform(extcap.Options{PayloadName: "shred"}, dlt)
+	assert.ErrorIs(t, err, extcap.ErrValueInvalid)
+}
@@ -0,0 +1,97 @@
+package extcap
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/urfave/cli/v2"
+)
+
+// Profiles file is a JSON object with named sets of option values, e.g.
+//
+//	{
+//	  "office": {"remote-host": "10.0.0.1", "remote-port": 22, "remote-username": "admin"},
+//	  "lab":    {"remote-host": "192.168.1.10", "remote-port": 2222}
+//	}
+//
+// Values passed on the command line take precedence over values from the profile.
+
+// defaultProfilesFile returns location of the profiles file used when App.ProfilesFile is not set
+func defaultProfilesFile(appName string) string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, appName, "profiles.json")
+}
+
+// loadProfile reads option values of the named profile from the profiles file
+func loadProfile(path, name string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read profiles: %w", err)
+	}
+
+	var profiles map[string]map[string]json.RawMessage
+	if err = json.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("unable to parse profiles %s: %w", path, err)
+	}
+
+	profile, ok := profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrProfileNotFound, name)
+	}
+
+	values := make(map[string]string, len(profile))
+	for opt, raw := range profile {
+		var str string
+		if err = json.Unmarshal(raw, &str); err != nil {
+			// numbers and booleans are used as written in the file
+			str = string(raw)
+		}
+		values[opt] = str
+	}
+
+	return values, nil
+}
+
+// applyProfile sets values of options from the profile selected with --profile,
+// options passed on the command line are left untouched
+func (extapp App) applyProfile(ctx *cli.Context) error {
+	if !ctx.IsSet("profile") {
+		return nil
+	}
+
+	path := extapp.ProfilesFile
+	if path == "" {
+		path = defaultProfilesFile(ctx.App.Name)
+	}
+
+	values, err := loadProfile(path, ctx.String("profile"))
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if _, ok := extapp.registeredOpts[name]; !ok {
+			return fmt.Errorf("profile %s: %w: %s", ctx.String("profile"), ErrUnknownOption, name)
+		}
+		if ctx.IsSet(name) {
+			continue
+		}
+		if err = ctx.Set(name, values[name]); err != nil {
+			return fmt.Errorf("profile %s: option --%s: %w", ctx.String("profile"), name, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package extcap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// PacketSource returns captured packets one by one, io.EOF ends the capture.
+// Errors with Timeout() returning true are treated as no packet being available yet.
+type PacketSource interface {
+	ReadPacket() (ts time.Time, data []byte, origLen int, err error)
+}
+
+// PacketSourceFunc is a function implementing PacketSource
+type PacketSourceFunc func() (ts time.Time, data []byte, origLen int, err error)
+
+// ReadPacket calls f
+func (f PacketSourceFunc) ReadPacket() (time.Time, []byte, int, error) {
+	return f()
+}
+
+// PumpOptions configure Pump, zero values disable the corresponding step
+type PumpOptions struct {
+	// Filter decides if the packet is written, e.g. Filter.Match
+	Filter func(data []byte) bool
+	// Sampler keeps a sample of the packets passing the filter
+	Sampler *Sampler
+	// Snaplen truncates packet data, the original length is kept
+	Snaplen int
+}
+
+// Pump reads packets from src and writes them to w until the context is done or src returns io.EOF,
+// in both cases nil is returned. Read errors other than timeouts and write errors stop it and are returned.
+// The context is checked between packets, so src should not block indefinitely.
+func Pump(ctx context.Context, src PacketSource, w PacketWriter, opts PumpOptions) error {
+	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		ts, data, origLen, err := src.ReadPacket()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			var timeout interface{ Timeout() bool }
+			if errors.As(err, &timeout) && timeout.Timeout() {
+				continue
+			}
+			return fmt.Errorf("unable to read packet: %w", err)
+		}
+
+		if opts.Filter != nil && !opts.Filter(data) {
+			continue
+		}
+		if opts.Sampler != nil && !opts.Sampler.Keep(data) {
+			continue
+		}
+		if origLen < len(data) {
+			origLen = len(data)
+		}
+		if opts.Snaplen > 0 && len(data) > opts.Snaplen {
+			data = data[:opts.Snaplen]
+		}
+
+		if err = w.WritePacket(ts, data, origLen); err != nil {
+			return fmt.Errorf("unable to write packet: %w", err)
+		}
+	}
+}
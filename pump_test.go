@@ -0,0 +1,89 @@
+package extcap
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingPacketWriter keeps written packets
+type recordingPacketWriter struct {
+	packets [][]byte
+	origLen []int
+}
+
+func (w *recordingPacketWriter) WritePacket(_ time.Time, data []byte, origLen int) error {
+	w.packets = append(w.packets, append([]byte(nil), data...))
+	w.origLen = append(w.origLen, origLen)
+	return nil
+}
+
+// batchRecordingWriter is recordingPacketWriter writing batches, it counts the batches and the flushes
+type batchRecordingWriter struct {
+	recordingPacketWriter
+	batches int
+	flushes int
+}
+
+func (w *batchRecordingWriter) WritePackets(packets []Packet) error {
+	w.batches++
+	for _, p := range packets {
+		if err := w.WritePacket(p.Timestamp, p.Data, p.OrigLen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *batchRecordingWriter) Flush() error {
+	w.flushes++
+	return nil
+}
+
+// sliceSource returns the packets and then the error
+func sliceSource(err error, packets ...[]byte) PacketSource {
+	return PacketSourceFunc(func() (time.Time, []byte, int, error) {
+		if len(packets) == 0 {
+			return time.Time{}, nil, 0, err
+		}
+		data := packets[0]
+		packets = packets[1:]
+		return time.Now(), data, 0, nil
+	})
+}
+
+func TestPump(t *testing.T) {
+	w := new(recordingPacketWriter)
+	src := sliceSource(io.EOF, []byte{1, 2, 3}, []byte{0}, []byte{4, 5, 6})
+	err := Pump(context.Background(), src, w, PumpOptions{
+		Filter:  func(data []byte) bool { return data[0] != 0 },
+		Snaplen: 2,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{{1, 2}, {4, 5}}, w.packets)
+	assert.Equal(t, []int{3, 3}, w.origLen)
+
+	readErr := errors.New("device gone")
+	err = Pump(context.Background(), sliceSource(readErr), w, PumpOptions{})
+	assert.ErrorIs(t, err, readErr)
+}
+
+func TestPumpCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	timeouts := 0
+	src := PacketSourceFunc(func() (time.Time, []byte, int, error) {
+		timeouts++
+		if timeouts == 3 {
+			cancel()
+		}
+		return time.Time{}, nil, 0, os.ErrDeadlineExceeded
+	})
+
+	assert.NoError(t, Pump(ctx, src, new(recordingPacketWriter), PumpOptions{}))
+	assert.Equal(t, 3, timeouts)
+}
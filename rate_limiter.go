@@ -0,0 +1,109 @@
+package extcap
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimit is the maximum rate of RateLimitedWriter, zero values are not limited
+type RateLimit struct {
+	// PacketsPerSecond limits the number of packets written per second
+	PacketsPerSecond float64
+	// BytesPerSecond limits the number of packet data bytes written per second
+	BytesPerSecond float64
+}
+
+// RateLimitedWriter paces packets written to the underlying writer, so replayed or synthetic traffic
+// is streamed at a realistic speed. WritePacket waits until the packet fits the rate.
+type RateLimitedWriter struct {
+	w     PacketWriter
+	limit RateLimit
+
+	// ctx stops the waiting, it is cancelled by Close
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+
+	mu   sync.Mutex
+	next time.Time
+	// now and sleep are replaced in tests
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) bool
+}
+
+// NewRateLimitedWriter creates RateLimitedWriter writing to w at most at the rate of limit.
+// Waiting for the rate stops when ctx is done, e.g. CaptureSession.Context, or the writer is closed.
+func NewRateLimitedWriter(ctx context.Context, w PacketWriter, limit RateLimit) *RateLimitedWriter {
+	ctx, cancel := context.WithCancelCause(ctx)
+	return &RateLimitedWriter{w: w, limit: limit, ctx: ctx, cancel: cancel, now: time.Now, sleep: sleepContext}
+}
+
+// WritePacket waits for the packet's turn and writes it. If the context is done or the writer is closed
+// while waiting, the packet is not written and the cause is returned, ErrWriterClosed for Close.
+func (rw *RateLimitedWriter) WritePacket(ts time.Time, data []byte, origLen int) error {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+
+	if err := rw.wait(1, len(data)); err != nil {
+		return err
+	}
+	return rw.w.WritePacket(ts, data, origLen)
+}
+
+// WritePackets waits for the turn of the batch and writes it at once, the following write
+// waits until the whole batch fits the rate
+func (rw *RateLimitedWriter) WritePackets(packets []Packet) error {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+
+	var size int
+	for _, p := range packets {
+		size += len(p.Data)
+	}
+	if err := rw.wait(len(packets), size); err != nil {
+		return err
+	}
+	return WritePackets(rw.w, packets)
+}
+
+// wait waits for the turn and moves the next turn by the cost of the packets of size bytes
+func (rw *RateLimitedWriter) wait(packets, size int) error {
+	if rw.ctx.Err() != nil {
+		return context.Cause(rw.ctx)
+	}
+	now := rw.now()
+	if rw.next.After(now) {
+		if !rw.sleep(rw.ctx, rw.next.Sub(now)) {
+			return context.Cause(rw.ctx)
+		}
+	} else {
+		// idle time is not saved up for bursts
+		rw.next = now
+	}
+
+	var cost time.Duration
+	if rw.limit.PacketsPerSecond > 0 {
+		cost = time.Duration(float64(packets) * float64(time.Second) / rw.limit.PacketsPerSecond)
+	}
+	if rw.limit.BytesPerSecond > 0 {
+		cost = max(cost, time.Duration(float64(size)*float64(time.Second)/rw.limit.BytesPerSecond))
+	}
+	rw.next = rw.next.Add(cost)
+	return nil
+}
+
+// Flush flushes the limited writer without waiting for the rate limit
+func (rw *RateLimitedWriter) Flush() error {
+	return flushWriter(rw.w)
+}
+
+// ReportDropped passes the drops to the limited writer without waiting for the rate limit
+func (rw *RateLimitedWriter) ReportDropped(id int, n uint64) error {
+	return reportDropped(rw.w, id, n)
+}
+
+// Close stops the waiting write and closes the limited writer
+func (rw *RateLimitedWriter) Close() error {
+	rw.cancel(ErrWriterClosed)
+	return closeWriter(rw.w)
+}
@@ -0,0 +1,73 @@
+package extcap
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitedWriter(t *testing.T) {
+	now := time.Unix(0, 0)
+	var waited []time.Duration
+
+	w := new(recordingPacketWriter)
+	rw := NewRateLimitedWriter(context.Background(), w, RateLimit{PacketsPerSecond: 10, BytesPerSecond: 1000})
+	rw.now = func() time.Time { return now }
+	rw.sleep = func(_ context.Context, d time.Duration) bool {
+		waited = append(waited, d)
+		now = now.Add(d)
+		return true
+	}
+
+	assert.NoError(t, rw.WritePacket(now, make([]byte, 10), 10))
+	// 10 packets per second
+	assert.NoError(t, rw.WritePacket(now, make([]byte, 500), 500))
+	// 500 bytes at 1000 bytes per second
+	assert.NoError(t, rw.WritePacket(now, make([]byte, 10), 10))
+
+	assert.Equal(t, []time.Duration{100 * time.Millisecond, 500 * time.Millisecond}, waited)
+	assert.Len(t, w.packets, 3)
+}
+
+func TestRateLimitedWriterBatch(t *testing.T) {
+	now := time.Unix(0, 0)
+	var waited []time.Duration
+
+	w := new(batchRecordingWriter)
+	rw := NewRateLimitedWriter(context.Background(), w, RateLimit{PacketsPerSecond: 10})
+	rw.now = func() time.Time { return now }
+	rw.sleep = func(_ context.Context, d time.Duration) bool {
+		waited = append(waited, d)
+		now = now.Add(d)
+		return true
+	}
+
+	assert.NoError(t, rw.WritePackets([]Packet{{Data: []byte{1}}, {Data: []byte{2}}, {Data: []byte{3}}}))
+	// the next write waits for the three packets of the batch
+	assert.NoError(t, rw.WritePacket(now, []byte{4}, 1))
+	assert.NoError(t, rw.Flush())
+
+	assert.Equal(t, []time.Duration{300 * time.Millisecond}, waited)
+	assert.Equal(t, 1, w.batches)
+	assert.Equal(t, 1, w.flushes)
+	assert.Len(t, w.packets, 4)
+}
+
+func TestRateLimitedWriterStop(t *testing.T) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	w := new(recordingPacketWriter)
+	rw := NewRateLimitedWriter(ctx, w, RateLimit{BytesPerSecond: 1})
+
+	// the second packet waits for an hour
+	assert.NoError(t, rw.WritePacket(time.Now(), make([]byte, 3600), 3600))
+	time.AfterFunc(10*time.Millisecond, func() { cancel(ErrAutostop) })
+	assert.ErrorIs(t, rw.WritePacket(time.Now(), []byte{1}, 1), ErrAutostop)
+
+	rw = NewRateLimitedWriter(context.Background(), w, RateLimit{BytesPerSecond: 1})
+	assert.NoError(t, rw.WritePacket(time.Now(), make([]byte, 3600), 3600))
+	time.AfterFunc(10*time.Millisecond, func() { _ = rw.Close() })
+	assert.ErrorIs(t, rw.WritePacket(time.Now(), []byte{1}, 1), ErrWriterClosed)
+	assert.Len(t, w.packets, 2)
+}
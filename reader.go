@@ -0,0 +1,389 @@
+package extcap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+const (
+	pcapngPacket       = 0x00000002
+	pcapngSimplePacket = 0x00000003
+
+	// maxRecordSize limits the memory allocated for one record of malformed file
+	maxRecordSize = 64 << 20
+)
+
+// Record is a packet read with PacketReader
+type Record struct {
+	Packet
+	// Interface is the pcapng interface ID of the packet, 0 for pcap
+	Interface int
+	// LinkType is the link type of the interface, only DLT.Number is set
+	LinkType DLT
+}
+
+// PacketReader reads packets of pcap or pcapng data, e.g. for replay or verifying written captures.
+// It implements PacketSource, so it can be used with Pump. The end of the data is io.EOF.
+type PacketReader interface {
+	PacketSource
+	// ReadRecord reads the next packet, its data is valid until the next read
+	ReadRecord() (Record, error)
+	// LinkType returns the link type of the capture, of the first interface for pcapng
+	LinkType() DLT
+}
+
+// NewPacketReader creates PcapReader or PcapngReader reading r, the format is detected from the first bytes
+func NewPacketReader(r io.Reader) (PacketReader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(4)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCapture, err)
+	}
+	switch {
+	case binary.LittleEndian.Uint32(magic) == pcapngSectionHeader:
+		return NewPcapngReader(br)
+	default:
+		return NewPcapReader(br)
+	}
+}
+
+// PcapReader reads the pcap format in both byte orders and timestamp resolutions
+type PcapReader struct {
+	r        io.Reader
+	order    binary.ByteOrder
+	nanosec  bool
+	linkType int
+	snaplen  uint32
+	header   [16]byte
+	buf      []byte
+}
+
+// NewPcapReader reads the file header of r
+func NewPcapReader(r io.Reader) (*PcapReader, error) {
+	var header [24]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCapture, err)
+	}
+
+	pr := &PcapReader{r: r}
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		switch order.Uint32(header[0:]) {
+		case pcapMagicMicroseconds:
+			pr.order = order
+		case pcapMagicNanoseconds:
+			pr.order, pr.nanosec = order, true
+		}
+	}
+	if pr.order == nil {
+		return nil, fmt.Errorf("%w: unknown magic %x", ErrInvalidCapture, header[0:4])
+	}
+	pr.snaplen = pr.order.Uint32(header[16:])
+	pr.linkType = int(pr.order.Uint32(header[20:]) & 0xffff)
+	return pr, nil
+}
+
+// LinkType returns the link type from the file header
+func (pr *PcapReader) LinkType() DLT {
+	return DLT{Number: pr.linkType}
+}
+
+// Snaplen returns the snapshot length from the file header
+func (pr *PcapReader) Snaplen() int {
+	return int(pr.snaplen)
+}
+
+// ReadRecord reads the next packet
+func (pr *PcapReader) ReadRecord() (Record, error) {
+	ts, data, origLen, err := pr.ReadPacket()
+	if err != nil {
+		return Record{}, err
+	}
+	return Record{Packet: Packet{Timestamp: ts, Data: data, OrigLen: origLen}, LinkType: pr.LinkType()}, nil
+}
+
+// ReadPacket reads the next packet, the data is valid until the next call
+func (pr *PcapReader) ReadPacket() (time.Time, []byte, int, error) {
+	if _, err := io.ReadFull(pr.r, pr.header[:]); err != nil {
+		return time.Time{}, nil, 0, truncatedError(err)
+	}
+	sec, frac := pr.order.Uint32(pr.header[0:]), pr.order.Uint32(pr.header[4:])
+	length, origLen := pr.order.Uint32(pr.header[8:]), pr.order.Uint32(pr.header[12:])
+	if length > maxRecordSize {
+		return time.Time{}, nil, 0, fmt.Errorf("%w: record of %d bytes", ErrInvalidCapture, length)
+	}
+
+	data, err := readInto(pr.r, &pr.buf, int(length))
+	if err != nil {
+		return time.Time{}, nil, 0, err
+	}
+	if !pr.nanosec {
+		frac *= 1000
+	}
+	return time.Unix(int64(sec), int64(frac)), data, int(origLen), nil
+}
+
+// PcapngReader reads the packets of all sections and interfaces of the pcapng format, other blocks are skipped
+type PcapngReader struct {
+	r          io.Reader
+	order      binary.ByteOrder
+	interfaces []pcapngReaderInterface
+	header     [8]byte
+	buf        []byte
+}
+
+type pcapngReaderInterface struct {
+	linkType int
+	snaplen  uint32
+	// units per second of the timestamps
+	resolution uint64
+}
+
+// NewPcapngReader reads the blocks of r up to the first interface description
+func NewPcapngReader(r io.Reader) (*PcapngReader, error) {
+	pr := &PcapngReader{r: r}
+	blockType, body, err := pr.readBlock()
+	if err != nil {
+		return nil, err
+	}
+	if blockType != pcapngSectionHeader {
+		return nil, fmt.Errorf("%w: missing section header", ErrInvalidCapture)
+	}
+	if err = pr.readSectionHeader(body); err != nil {
+		return nil, err
+	}
+
+	for len(pr.interfaces) == 0 {
+		blockType, body, err := pr.readBlock()
+		if err != nil {
+			return nil, fmt.Errorf("%w: no interface description: %w", ErrInvalidCapture, err)
+		}
+		switch blockType {
+		case pcapngInterfaceDescription:
+			err = pr.readInterface(body)
+		case pcapngEnhancedPacket, pcapngPacket, pcapngSimplePacket:
+			err = fmt.Errorf("%w: packet before interface description", ErrInvalidCapture)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return pr, nil
+}
+
+// LinkType returns the link type of the first interface
+func (pr *PcapngReader) LinkType() DLT {
+	if len(pr.interfaces) == 0 {
+		return DLT{}
+	}
+	return DLT{Number: pr.interfaces[0].linkType}
+}
+
+// Interfaces returns the link types of the interfaces described so far in the current section,
+// indexed by the interface ID
+func (pr *PcapngReader) Interfaces() []DLT {
+	dlts := make([]DLT, len(pr.interfaces))
+	for i, iface := range pr.interfaces {
+		dlts[i] = DLT{Number: iface.linkType}
+	}
+	return dlts
+}
+
+// readBlock reads the next block, the section header block also sets the byte order
+func (pr *PcapngReader) readBlock() (uint32, []byte, error) {
+	if _, err := io.ReadFull(pr.r, pr.header[:]); err != nil {
+		return 0, nil, truncatedError(err)
+	}
+	if binary.LittleEndian.Uint32(pr.header[0:]) == pcapngSectionHeader {
+		var magic [4]byte
+		if _, err := io.ReadFull(pr.r, magic[:]); err != nil {
+			return 0, nil, truncatedError(err)
+		}
+		switch {
+		case binary.LittleEndian.Uint32(magic[:]) == pcapngByteOrderMagic:
+			pr.order = binary.LittleEndian
+		case binary.BigEndian.Uint32(magic[:]) == pcapngByteOrderMagic:
+			pr.order = binary.BigEndian
+		default:
+			return 0, nil, fmt.Errorf("%w: unknown byte order magic %x", ErrInvalidCapture, magic)
+		}
+		length := pr.order.Uint32(pr.header[4:])
+		if length < 28 || length%4 != 0 || length > maxRecordSize {
+			return 0, nil, fmt.Errorf("%w: section header of %d bytes", ErrInvalidCapture, length)
+		}
+		body, err := readInto(pr.r, &pr.buf, int(length)-12)
+		if err != nil {
+			return 0, nil, err
+		}
+		return pcapngSectionHeader, body[:len(body)-4], nil
+	}
+
+	if pr.order == nil {
+		return 0, nil, fmt.Errorf("%w: missing section header", ErrInvalidCapture)
+	}
+	blockType, length := pr.order.Uint32(pr.header[0:]), pr.order.Uint32(pr.header[4:])
+	if length < 12 || length%4 != 0 || length > maxRecordSize {
+		return 0, nil, fmt.Errorf("%w: block of %d bytes", ErrInvalidCapture, length)
+	}
+	body, err := readInto(pr.r, &pr.buf, int(length)-8)
+	if err != nil {
+		return 0, nil, err
+	}
+	return blockType, body[:len(body)-4], nil
+}
+
+// readSectionHeader starts a new section, interfaces of the previous one are forgotten
+func (pr *PcapngReader) readSectionHeader(body []byte) error {
+	if len(body) < 12 {
+		return fmt.Errorf("%w: short section header", ErrInvalidCapture)
+	}
+	if major := pr.order.Uint16(body[0:]); major != 1 {
+		return fmt.Errorf("%w: unsupported pcapng version %d", ErrInvalidCapture, major)
+	}
+	pr.interfaces = pr.interfaces[:0]
+	return nil
+}
+
+func (pr *PcapngReader) readInterface(body []byte) error {
+	if len(body) < 8 {
+		return fmt.Errorf("%w: short interface description", ErrInvalidCapture)
+	}
+	iface := pcapngReaderInterface{
+		linkType:   int(pr.order.Uint16(body[0:])),
+		snaplen:    pr.order.Uint32(body[4:]),
+		resolution: 1e6,
+	}
+	for opts := body[8:]; len(opts) >= 4; {
+		code, length := pr.order.Uint16(opts[0:]), int(pr.order.Uint16(opts[2:]))
+		if code == pcapngOptEndOfOpt || len(opts) < 4+length {
+			break
+		}
+		if code == pcapngOptTsresol && length >= 1 {
+			iface.resolution = tsresolUnits(opts[4])
+		}
+		opts = opts[4+pad4(length):]
+	}
+	pr.interfaces = append(pr.interfaces, iface)
+	return nil
+}
+
+// tsresolUnits returns units per second for the if_tsresol value
+func tsresolUnits(v byte) uint64 {
+	exp := uint64(v & 0x7f)
+	if v&0x80 != 0 {
+		if exp > 63 {
+			exp = 63
+		}
+		return 1 << exp
+	}
+	units := uint64(1)
+	for i := uint64(0); i < exp && units <= math.MaxUint64/10; i++ {
+		units *= 10
+	}
+	return units
+}
+
+// ReadRecord reads the next packet of any interface, packets of Simple Packet Blocks have zero timestamp
+func (pr *PcapngReader) ReadRecord() (Record, error) {
+	ts, data, origLen, id, err := pr.readPacket()
+	if err != nil {
+		return Record{}, err
+	}
+	return Record{
+		Packet:    Packet{Timestamp: ts, Data: data, OrigLen: origLen},
+		Interface: id,
+		LinkType:  DLT{Number: pr.interfaces[id].linkType},
+	}, nil
+}
+
+// ReadPacket reads the next packet of any interface, the data is valid until the next call
+func (pr *PcapngReader) ReadPacket() (time.Time, []byte, int, error) {
+	ts, data, origLen, _, err := pr.readPacket()
+	return ts, data, origLen, err
+}
+
+// readPacket reads the next packet and returns also its interface ID
+func (pr *PcapngReader) readPacket() (time.Time, []byte, int, int, error) {
+	for {
+		blockType, body, err := pr.readBlock()
+		if err != nil {
+			return time.Time{}, nil, 0, 0, err
+		}
+
+		switch blockType {
+		case pcapngSectionHeader:
+			err = pr.readSectionHeader(body)
+		case pcapngInterfaceDescription:
+			err = pr.readInterface(body)
+		case pcapngEnhancedPacket, pcapngPacket:
+			return pr.timestampedPacket(blockType, body)
+		case pcapngSimplePacket:
+			if len(body) < 4 || len(pr.interfaces) == 0 {
+				return time.Time{}, nil, 0, 0, fmt.Errorf("%w: invalid simple packet", ErrInvalidCapture)
+			}
+			origLen := int(pr.order.Uint32(body[0:]))
+			length := min(origLen, len(body)-4)
+			if snaplen := int(pr.interfaces[0].snaplen); snaplen > 0 {
+				length = min(length, snaplen)
+			}
+			return time.Time{}, body[4 : 4+length], origLen, 0, nil
+		}
+		if err != nil {
+			return time.Time{}, nil, 0, 0, err
+		}
+	}
+}
+
+// timestampedPacket decodes Enhanced Packet Block or the obsolete Packet Block
+func (pr *PcapngReader) timestampedPacket(blockType uint32, body []byte) (time.Time, []byte, int, int, error) {
+	if len(body) < 20 {
+		return time.Time{}, nil, 0, 0, fmt.Errorf("%w: short packet block", ErrInvalidCapture)
+	}
+	var id int
+	if blockType == pcapngPacket {
+		id = int(pr.order.Uint16(body[0:]))
+	} else {
+		id = int(pr.order.Uint32(body[0:]))
+	}
+	if id >= len(pr.interfaces) {
+		return time.Time{}, nil, 0, 0, fmt.Errorf("%w: packet of unknown interface %d", ErrInvalidCapture, id)
+	}
+
+	units := uint64(pr.order.Uint32(body[4:]))<<32 | uint64(pr.order.Uint32(body[8:]))
+	length, origLen := int(pr.order.Uint32(body[12:])), int(pr.order.Uint32(body[16:]))
+	if length > len(body)-20 {
+		return time.Time{}, nil, 0, 0, fmt.Errorf("%w: packet of %d bytes in block of %d", ErrInvalidCapture, length, len(body))
+	}
+
+	res := pr.interfaces[id].resolution
+	sec, nsec := units/res, units%res
+	if res <= 1e9 {
+		nsec = nsec * 1e9 / res
+	} else {
+		nsec /= res / 1e9
+	}
+	return time.Unix(int64(sec), int64(nsec)), body[20 : 20+length], origLen, id, nil
+}
+
+// readInto reads n bytes into the reused buffer
+func readInto(r io.Reader, buf *[]byte, n int) ([]byte, error) {
+	if cap(*buf) < n {
+		*buf = make([]byte, n)
+	}
+	b := (*buf)[:n]
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, truncatedError(err)
+	}
+	return b, nil
+}
+
+// truncatedError keeps io.EOF at record boundary and wraps unexpected end of data with ErrInvalidCapture
+func truncatedError(err error) error {
+	if err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("%w: %w", ErrInvalidCapture, err)
+	}
+	return err
+}
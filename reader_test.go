@@ -0,0 +1,120 @@
+package extcap
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPcapReader(t *testing.T) {
+	ts := time.Unix(1700000000, 123456789)
+	for name, opts := range map[string][]WriterOption{
+		"microseconds": {ByteOrder(binary.BigEndian)},
+		"nanoseconds":  {NanosecondResolution()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			w, err := NewPcapWriter(buf, DLT{Number: 105}, append(opts, SnapshotLength(100))...)
+			require.NoError(t, err)
+			require.NoError(t, w.WritePacket(ts, []byte{1, 2, 3}, 60))
+			require.NoError(t, w.Close())
+
+			r, err := NewPacketReader(buf)
+			require.NoError(t, err)
+			assert.Equal(t, DLT{Number: 105}, r.LinkType())
+			assert.Equal(t, 100, r.(*PcapReader).Snaplen())
+
+			rec, err := r.ReadRecord()
+			require.NoError(t, err)
+			assert.Equal(t, []byte{1, 2, 3}, rec.Data)
+			assert.Equal(t, 60, rec.OrigLen)
+			if name == "microseconds" {
+				assert.Equal(t, ts.Truncate(time.Microsecond), rec.Timestamp)
+			} else {
+				assert.Equal(t, ts, rec.Timestamp)
+			}
+
+			_, err = r.ReadRecord()
+			assert.Equal(t, io.EOF, err)
+		})
+	}
+}
+
+func TestPcapngReader(t *testing.T) {
+	ts := time.Unix(1700000000, 123456789)
+	buf := new(bytes.Buffer)
+	w, err := NewPcapngWriter(buf, DLT{Number: 1}, NanosecondResolution())
+	require.NoError(t, err)
+	id, err := w.AddInterface(DLT{Number: 105}, "wlan0")
+	require.NoError(t, err)
+	require.NoError(t, w.WritePacket(ts, []byte{1}, 1))
+	require.NoError(t, w.WriteNameResolution(NameRecord{IP: net.IPv4(10, 0, 0, 1), Names: []string{"router"}}))
+	require.NoError(t, w.WriteInterfacePacket(id, ts.Add(time.Second), []byte{2, 3}, 2))
+	require.NoError(t, w.Close())
+
+	r, err := NewPcapngReader(buf)
+	require.NoError(t, err)
+	assert.Equal(t, DLT{Number: 1}, r.LinkType())
+
+	rec, err := r.ReadRecord()
+	require.NoError(t, err)
+	assert.Equal(t, Record{Packet: Packet{Timestamp: ts, Data: []byte{1}, OrigLen: 1}, LinkType: DLT{Number: 1}}, rec)
+
+	rec, err = r.ReadRecord()
+	require.NoError(t, err)
+	assert.Equal(t, 1, rec.Interface)
+	assert.Equal(t, DLT{Number: 105}, rec.LinkType)
+	assert.Equal(t, ts.Add(time.Second), rec.Timestamp)
+	assert.Equal(t, []DLT{{Number: 1}, {Number: 105}}, r.Interfaces())
+
+	_, err = r.ReadRecord()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestPacketReaderErrors(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w, err := NewPcapngWriter(buf, DLT{Number: 1})
+	require.NoError(t, err)
+	require.NoError(t, w.WritePacket(time.Now(), []byte{1, 2, 3, 4}, 4))
+	require.NoError(t, w.Flush())
+
+	r, err := NewPacketReader(bytes.NewReader(buf.Bytes()[:buf.Len()-2]))
+	require.NoError(t, err)
+	_, err = r.ReadRecord()
+	assert.ErrorIs(t, err, ErrInvalidCapture)
+
+	_, err = NewPacketReader(bytes.NewReader([]byte{1, 2, 3, 4}))
+	assert.ErrorIs(t, err, ErrInvalidCapture)
+}
+
+func TestPcapngReaderPcapInput(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w, err := NewPcapWriter(buf, DLT{Number: 1})
+	require.NoError(t, err)
+	require.NoError(t, w.WritePacket(time.Now(), []byte{1, 2, 3, 4}, 4))
+
+	_, err = NewPcapngReader(buf)
+	assert.ErrorIs(t, err, ErrInvalidCapture)
+}
+
+func TestPumpPacketReader(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w, err := NewPcapWriter(buf, DLT{Number: 1})
+	require.NoError(t, err)
+	require.NoError(t, w.WritePacket(time.Now(), []byte{1}, 1))
+	require.NoError(t, w.WritePacket(time.Now(), []byte{2}, 1))
+	require.NoError(t, w.Close())
+
+	r, err := NewPacketReader(buf)
+	require.NoError(t, err)
+	out := new(recordingPacketWriter)
+	assert.NoError(t, Pump(context.Background(), r, out, PumpOptions{}))
+	assert.Equal(t, [][]byte{{1}, {2}}, out.packets)
+}
@@ -0,0 +1,48 @@
+package extcap
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Replay reads packets of pcap or pcapng data from r and writes them to w until the end of the data or ctx is done,
+// in both cases nil is returned. It is useful for demo interfaces and testing dissectors.
+//
+// The inter-packet timing is scaled by speed, e.g. 1 keeps the original timing and 2 replays twice as fast.
+// Packets are written as fast as possible if speed is not positive. The original timestamps are kept,
+// all interfaces of pcapng data are written to w as one.
+func Replay(ctx context.Context, r io.Reader, w PacketWriter, speed float64) error {
+	src, err := NewPacketReader(r)
+	if err != nil {
+		return err
+	}
+
+	var first time.Time
+	var start time.Time
+	for ctx.Err() == nil {
+		ts, data, origLen, err := src.ReadPacket()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("unable to read packet: %w", err)
+		}
+
+		if speed > 0 {
+			if start.IsZero() {
+				first, start = ts, time.Now()
+			}
+			// scheduling from the start does not accumulate the drift of the sleeps
+			due := start.Add(time.Duration(float64(ts.Sub(first)) / speed))
+			if wait := time.Until(due); wait > 0 && !sleepContext(ctx, wait) {
+				return nil
+			}
+		}
+		if err = w.WritePacket(ts, data, origLen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
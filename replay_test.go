@@ -0,0 +1,34 @@
+package extcap
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplay(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	for name, format := range map[string]PacketWriterFunc{"pcap": PcapFormat, "pcapng": PcapngFormat} {
+		t.Run(name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			w, err := format(buf, DLT{Number: 1}, NanosecondResolution())
+			require.NoError(t, err)
+			require.NoError(t, w.WritePacket(start, []byte{1, 2}, 10))
+			require.NoError(t, w.WritePacket(start.Add(40*time.Millisecond+5), []byte{3}, 1))
+			require.NoError(t, closeWriter(w))
+
+			out := new(recordingPacketWriter)
+			began := time.Now()
+			require.NoError(t, Replay(context.Background(), buf, out, 2))
+			assert.GreaterOrEqual(t, time.Since(began), 20*time.Millisecond)
+			assert.Equal(t, [][]byte{{1, 2}, {3}}, out.packets)
+			assert.Equal(t, []int{10, 1}, out.origLen)
+		})
+	}
+
+	assert.ErrorIs(t, Replay(context.Background(), bytes.NewReader([]byte("not a capture file")), new(recordingPacketWriter), 0), ErrInvalidCapture)
+}
@@ -0,0 +1,118 @@
+package extcap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// Default values of Backoff fields
+const (
+	DefaultBackoffInitial    = 100 * time.Millisecond
+	DefaultBackoffMax        = 30 * time.Second
+	DefaultBackoffMultiplier = 2
+)
+
+// Backoff configures Retry, the zero value retries forever with delays from DefaultBackoffInitial to DefaultBackoffMax
+type Backoff struct {
+	// Initial is the delay before the first retry
+	Initial time.Duration
+	// Max is the maximal delay between the attempts
+	Max time.Duration
+	// Multiplier increases the delay after each failed attempt
+	Multiplier float64
+	// Jitter randomizes the delay by up to the fraction of it in both directions, e.g. 0.2 for ±20%
+	Jitter float64
+	// MaxAttempts is the number of attempts before Retry gives up, 0 means no limit
+	MaxAttempts int
+	// OnRetry is called after failed attempt with the attempt number starting at 1, the error and the delay
+	// before the next attempt, e.g. to log the disconnect. Optional.
+	OnRetry func(attempt int, err error, delay time.Duration)
+}
+
+// Delay returns the delay after the failed attempt, starting at 1, without jitter
+func (b Backoff) Delay(attempt int) time.Duration {
+	initial, maxDelay, multiplier := b.Initial, b.Max, b.Multiplier
+	if initial <= 0 {
+		initial = DefaultBackoffInitial
+	}
+	if maxDelay <= 0 {
+		maxDelay = DefaultBackoffMax
+	}
+	if multiplier < 1 {
+		multiplier = DefaultBackoffMultiplier
+	}
+
+	delay := float64(initial)
+	for i := 1; i < attempt && delay < float64(maxDelay); i++ {
+		delay *= multiplier
+	}
+	return min(time.Duration(delay), maxDelay)
+}
+
+func (b Backoff) jittered(attempt int) time.Duration {
+	delay := b.Delay(attempt)
+	if b.Jitter > 0 {
+		delay += time.Duration((rand.Float64()*2 - 1) * b.Jitter * float64(delay))
+	}
+	return max(delay, 0)
+}
+
+// permanentError stops Retry
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks the error as not worth retrying, Retry returns the wrapped error immediately
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err: err}
+}
+
+// Retry calls f until it succeeds, returns permanent error, the attempts are exhausted or ctx is done,
+// e.g. to reconnect to a remote source with the CaptureSession.Context. Delays between the attempts grow with b.
+// If ctx is done, context.Cause of it is returned.
+func Retry(ctx context.Context, b Backoff, f func(ctx context.Context) error) error {
+	for attempt := 1; ; attempt++ {
+		err := f(ctx)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return context.Cause(ctx)
+		}
+		var permanent permanentError
+		if errors.As(err, &permanent) {
+			return permanent.err
+		}
+		if b.MaxAttempts > 0 && attempt >= b.MaxAttempts {
+			return fmt.Errorf("%w after %d attempts: %w", ErrRetriesExhausted, attempt, err)
+		}
+
+		delay := b.jittered(attempt)
+		if b.OnRetry != nil {
+			b.OnRetry(attempt, err, delay)
+		}
+
+		if !sleepContext(ctx, delay) {
+			return context.Cause(ctx)
+		}
+	}
+}
+
+// sleepContext waits for the duration, it returns false if ctx was done before
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,51 @@
+package extcap
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	b := Backoff{Initial: time.Second, Max: 5 * time.Second}
+	assert.Equal(t, time.Second, b.Delay(1))
+	assert.Equal(t, 2*time.Second, b.Delay(2))
+	assert.Equal(t, 4*time.Second, b.Delay(3))
+	assert.Equal(t, 5*time.Second, b.Delay(4))
+	assert.Equal(t, 5*time.Second, b.Delay(100))
+}
+
+func TestRetry(t *testing.T) {
+	errDisconnected := errors.New("disconnected")
+	var retries []int
+	b := Backoff{Initial: time.Millisecond, Jitter: 0.5, OnRetry: func(attempt int, err error, _ time.Duration) {
+		assert.ErrorIs(t, err, errDisconnected)
+		retries = append(retries, attempt)
+	}}
+
+	calls := 0
+	err := Retry(context.Background(), b, func(context.Context) error {
+		if calls++; calls < 3 {
+			return errDisconnected
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, retries)
+
+	b.MaxAttempts = 2
+	err = Retry(context.Background(), b, func(context.Context) error { return errDisconnected })
+	assert.ErrorIs(t, err, ErrRetriesExhausted)
+	assert.ErrorIs(t, err, errDisconnected)
+
+	err = Retry(context.Background(), Backoff{}, func(context.Context) error { return Permanent(errDisconnected) })
+	assert.Equal(t, errDisconnected, err)
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(ErrAutostop)
+	err = Retry(ctx, Backoff{}, func(context.Context) error { return errDisconnected })
+	assert.Equal(t, ErrAutostop, err)
+}
@@ -0,0 +1,165 @@
+package extcap
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RotationOptions configure RotatingWriter, zero values disable the corresponding limit
+type RotationOptions struct {
+	// Pattern is the file name, "{n}" is replaced with the file number and "{time}" with the time the file was created.
+	// Without the placeholders "_{n}_{time}" is inserted before the extension, like dumpcap does.
+	Pattern string
+	// MaxSize is the size in bytes after which the next file is started
+	MaxSize int64
+	// MaxDuration is the time after which the next file is started
+	MaxDuration time.Duration
+	// MaxFiles is the number of files kept, the oldest file is removed when a new one is started
+	MaxFiles int
+}
+
+// RotatingWriter writes packets to a ring buffer of pcapng files, the next file is started
+// when the current one reaches the size or duration limit
+type RotatingWriter struct {
+	dlt  DLT
+	opts RotationOptions
+	wopt []WriterOption
+
+	mu      sync.Mutex
+	file    *os.File
+	writer  *PcapngWriter
+	size    int64
+	started time.Time
+	number  int
+	files   []string
+}
+
+// NewRotatingWriter creates RotatingWriter and the first file for packets of the link type of dlt
+func NewRotatingWriter(dlt DLT, opts RotationOptions, wopts ...WriterOption) (*RotatingWriter, error) {
+	if opts.Pattern == "" {
+		return nil, fmt.Errorf("%w: rotation pattern is empty", ErrValueInvalid)
+	}
+
+	rw := &RotatingWriter{dlt: dlt, opts: opts, wopt: wopts}
+	if err := rw.rotateLocked(time.Now()); err != nil {
+		return nil, err
+	}
+	return rw, nil
+}
+
+// WritePacket writes the packet to the current file, the next file is started first if a limit was reached
+func (rw *RotatingWriter) WritePacket(ts time.Time, data []byte, origLen int) error {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+
+	if rw.writer == nil {
+		return ErrWriterClosed
+	}
+	if rw.limitReached(time.Now()) {
+		if err := rw.rotateLocked(time.Now()); err != nil {
+			return err
+		}
+	}
+	return rw.writer.WritePacket(ts, data, origLen)
+}
+
+// ReportDropped reports the drops to the current file
+func (rw *RotatingWriter) ReportDropped(id int, n uint64) error {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+
+	if rw.writer == nil {
+		return ErrWriterClosed
+	}
+	return rw.writer.ReportDropped(id, n)
+}
+
+// Files returns names of the kept files, the current file is the last one
+func (rw *RotatingWriter) Files() []string {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+
+	return append([]string(nil), rw.files...)
+}
+
+// Close closes the current file, calling it again does nothing
+func (rw *RotatingWriter) Close() error {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+
+	return rw.closeLocked()
+}
+
+func (rw *RotatingWriter) limitReached(now time.Time) bool {
+	return (rw.opts.MaxSize > 0 && rw.size >= rw.opts.MaxSize) ||
+		(rw.opts.MaxDuration > 0 && now.Sub(rw.started) >= rw.opts.MaxDuration)
+}
+
+func (rw *RotatingWriter) closeLocked() error {
+	if rw.writer == nil {
+		return nil
+	}
+	err := errors.Join(rw.writer.Close(), rw.file.Close())
+	rw.writer, rw.file = nil, nil
+	return err
+}
+
+// rotateLocked closes the current file, starts the next one and removes files above the limit
+func (rw *RotatingWriter) rotateLocked(now time.Time) error {
+	if err := rw.closeLocked(); err != nil {
+		return err
+	}
+
+	rw.number++
+	name := rotationFileName(rw.opts.Pattern, rw.number, now)
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	rw.size = 0
+	writer, err := NewPcapngWriter(sizeCounter{w: f, size: &rw.size}, rw.dlt, rw.wopt...)
+	if err != nil {
+		return errors.Join(err, f.Close())
+	}
+	rw.file, rw.writer, rw.started = f, writer, now
+
+	rw.files = append(rw.files, name)
+	if rw.opts.MaxFiles > 0 && len(rw.files) > rw.opts.MaxFiles {
+		for _, old := range rw.files[:len(rw.files)-rw.opts.MaxFiles] {
+			if err := os.Remove(old); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+		}
+		rw.files = append([]string(nil), rw.files[len(rw.files)-rw.opts.MaxFiles:]...)
+	}
+	return nil
+}
+
+// rotationFileName expands the placeholders of the pattern
+func rotationFileName(pattern string, number int, now time.Time) string {
+	if !strings.Contains(pattern, "{n}") && !strings.Contains(pattern, "{time}") {
+		ext := filepath.Ext(pattern)
+		pattern = strings.TrimSuffix(pattern, ext) + "_{n}_{time}" + ext
+	}
+	return strings.NewReplacer(
+		"{n}", fmt.Sprintf("%05d", number),
+		"{time}", now.Format("20060102150405"),
+	).Replace(pattern)
+}
+
+// sizeCounter counts bytes written to the file
+type sizeCounter struct {
+	w    *os.File
+	size *int64
+}
+
+func (c sizeCounter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	*c.size += int64(n)
+	return n, err
+}
@@ -0,0 +1,41 @@
+package extcap
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotatingWriter(t *testing.T) {
+	dir := t.TempDir()
+	rw, err := NewRotatingWriter(DLT{Number: 1}, RotationOptions{
+		Pattern:  filepath.Join(dir, "capture-{n}.pcapng"),
+		MaxSize:  100,
+		MaxFiles: 2,
+	})
+	assert.NoError(t, err)
+
+	// headers and one packet exceed MaxSize, so every packet starts a new file
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, rw.WritePacket(time.Now(), make([]byte, 60), 60))
+	}
+	assert.NoError(t, rw.Close())
+	assert.NoError(t, rw.Close())
+
+	assert.Equal(t, []string{
+		filepath.Join(dir, "capture-00003.pcapng"),
+		filepath.Join(dir, "capture-00004.pcapng"),
+	}, rw.Files())
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func TestRotationFileName(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	assert.Equal(t, "out_00007_20240102030405.pcapng", rotationFileName("out.pcapng", 7, now))
+	assert.Equal(t, "out-00007", rotationFileName("out-{n}", 7, now))
+}
@@ -0,0 +1,101 @@
+package extcap
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Sampler keeps a sample of the packets, for high-volume sources where the full capture would overwhelm Wireshark.
+// It is safe to use from multiple goroutines.
+type Sampler struct {
+	every       uint64
+	probability float64
+
+	seen    atomic.Uint64
+	skipped atomic.Uint64
+	mu      sync.Mutex
+	rand    *rand.Rand
+}
+
+// NewSampler creates Sampler keeping every n-th packet, starting with the first one. Values below 2 keep all packets.
+func NewSampler(n uint64) *Sampler {
+	return &Sampler{every: max(n, 1)}
+}
+
+// NewProbabilisticSampler creates Sampler keeping every packet with probability p
+func NewProbabilisticSampler(p float64) *Sampler {
+	return &Sampler{probability: p, rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+// Keep reports if the packet belongs to the sample, the data is not inspected.
+// It has the signature of PumpOptions.Filter.
+func (s *Sampler) Keep([]byte) bool {
+	var keep bool
+	if s.rand != nil {
+		s.mu.Lock()
+		keep = s.rand.Float64() < s.probability
+		s.mu.Unlock()
+	} else {
+		keep = (s.seen.Add(1)-1)%s.every == 0
+	}
+
+	if !keep {
+		s.skipped.Add(1)
+	}
+	return keep
+}
+
+// Skipped returns number of packets left out of the sample
+func (s *Sampler) Skipped() uint64 {
+	return s.skipped.Load()
+}
+
+// SampledWriter writes to the underlying writer only the packets kept by the sampler
+type SampledWriter struct {
+	w       PacketWriter
+	sampler *Sampler
+}
+
+// NewSampledWriter creates SampledWriter writing packets kept by s to w
+func NewSampledWriter(w PacketWriter, s *Sampler) *SampledWriter {
+	return &SampledWriter{w: w, sampler: s}
+}
+
+// WritePacket writes the packet if it belongs to the sample
+func (sw *SampledWriter) WritePacket(ts time.Time, data []byte, origLen int) error {
+	if !sw.sampler.Keep(data) {
+		return nil
+	}
+	return sw.w.WritePacket(ts, data, origLen)
+}
+
+// WritePackets writes the packets of the batch belonging to the sample as one batch
+func (sw *SampledWriter) WritePackets(packets []Packet) error {
+	kept := make([]Packet, 0, len(packets))
+	for _, p := range packets {
+		if sw.sampler.Keep(p.Data) {
+			kept = append(kept, p)
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	return WritePackets(sw.w, kept)
+}
+
+// Flush flushes the sampled writer
+func (sw *SampledWriter) Flush() error {
+	return flushWriter(sw.w)
+}
+
+// ReportDropped passes the drops to the sampled writer, packets left out of the sample are not drops
+func (sw *SampledWriter) ReportDropped(id int, n uint64) error {
+	return reportDropped(sw.w, id, n)
+}
+
+// Close closes the sampled writer
+func (sw *SampledWriter) Close() error {
+	return closeWriter(sw.w)
+}
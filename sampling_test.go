@@ -0,0 +1,45 @@
+package extcap
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSampler(t *testing.T) {
+	w := new(recordingPacketWriter)
+	sw := NewSampledWriter(w, NewSampler(3))
+	for i := byte(0); i < 7; i++ {
+		assert.NoError(t, sw.WritePacket(time.Now(), []byte{i}, 1))
+	}
+	assert.Equal(t, [][]byte{{0}, {3}, {6}}, w.packets)
+	assert.Equal(t, uint64(4), sw.sampler.Skipped())
+
+	none := NewProbabilisticSampler(0)
+	all := NewProbabilisticSampler(1)
+	for i := 0; i < 100; i++ {
+		assert.False(t, none.Keep(nil))
+		assert.True(t, all.Keep(nil))
+	}
+}
+
+func TestPumpSampler(t *testing.T) {
+	w := new(recordingPacketWriter)
+	src := sliceSource(io.EOF, []byte{1}, []byte{2}, []byte{3}, []byte{4})
+	assert.NoError(t, Pump(context.Background(), src, w, PumpOptions{Sampler: NewSampler(2)}))
+	assert.Equal(t, [][]byte{{1}, {3}}, w.packets)
+}
+
+func TestSampledWriterBatch(t *testing.T) {
+	w := new(batchRecordingWriter)
+	sw := NewSampledWriter(w, NewSampler(2))
+
+	assert.NoError(t, sw.WritePackets([]Packet{{Data: []byte{0}}, {Data: []byte{1}}, {Data: []byte{2}}}))
+	assert.NoError(t, sw.Flush())
+	assert.Equal(t, [][]byte{{0}, {2}}, w.packets)
+	assert.Equal(t, 1, w.batches)
+	assert.Equal(t, 1, w.flushes)
+}
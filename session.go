@@ -0,0 +1,112 @@
+package extcap
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// CaptureSession holds everything the capture needs, it is passed to App.StartCapture
+type CaptureSession struct {
+	// Context is cancelled when capture should stop, e.g. when the toolbar button with StopCapture is pressed.
+	// If Wireshark closes the control pipe, context.Cause returns error matching ErrHostClosed,
+	// if writing to the fifo with Writer fails because Wireshark closed it, the cause matches ErrPipeClosed,
+	// if a condition of App.Autostop is reached, the cause is ErrAutostop.
+	Context context.Context
+
+	// Interface is the interface to capture on
+	Interface string
+
+	// Fifo is the pipe to write capture results to
+	Fifo io.WriteCloser
+
+	// Writer writes packets to the Fifo, nil if App.NewPacketWriter is not defined.
+	// Packets written with it are counted in the capture statistics. It wraps the created writer,
+	// the pcapng features are available with Pcapng.
+	Writer PacketWriter
+
+	// Filter is the capture filter, empty if not set
+	Filter string
+
+	// PacketFilter is the compiled Filter if App.UserSpaceFilter is set, e.g. for PumpOptions.Filter.
+	// It is nil if the filter is not set.
+	PacketFilter *Filter
+
+	// Options are the values of configuration options for capture on the interface
+	Options Options
+
+	// Control is the channel for the interface toolbar, nil if Wireshark did not provide control pipes
+	Control *ControlChannel
+
+	// writer is the writer created by App.NewPacketWriter, Writer wraps it
+	writer    PacketWriter
+	cancel    context.CancelCauseFunc
+	autostop  Autostop
+	start     time.Time
+	packets   atomic.Uint64
+	bytes     atomic.Uint64
+	dropped   atomic.Uint64
+	lastWrite atomic.Int64
+}
+
+// CountPacket adds packet of size bytes to the capture statistics, the capture is stopped with ErrAutostop
+// when the packet or byte limit of App.Autostop is reached
+func (s *CaptureSession) CountPacket(size int) {
+	packets := s.packets.Add(1)
+	bytes := s.bytes.Add(uint64(size))
+	s.lastWrite.Store(time.Now().UnixNano())
+	if s.autostop.reached(packets, bytes) && s.cancel != nil {
+		s.cancel(ErrAutostop)
+	}
+}
+
+// autostopReached reports if no more packets should be written because of App.Autostop
+func (s *CaptureSession) autostopReached() bool {
+	return s.autostop.reached(s.packets.Load(), s.bytes.Load())
+}
+
+// CountDropped adds n packets dropped by the source to the capture statistics and reports them to the Writer,
+// so the pcapng stream accounts them on interface 0
+func (s *CaptureSession) CountDropped(n uint64) {
+	s.dropped.Add(n)
+	if s.writer != nil {
+		// write errors are sticky, they are returned by the next write
+		_ = reportDropped(s.writer, 0, n)
+	}
+}
+
+// CaptureStats are the statistics of the capture
+type CaptureStats struct {
+	// Packets and Bytes are the packets written with CaptureSession.Writer or counted with CountPacket
+	Packets uint64
+	Bytes   uint64
+	// Dropped are the packets counted with CountDropped
+	Dropped uint64
+	// Start is the time the capture was started
+	Start time.Time
+	// LastWrite is the time the last packet was counted, zero if there was none
+	LastWrite time.Time
+}
+
+// Stats returns the statistics of the capture, it is safe to call from any goroutine
+func (s *CaptureSession) Stats() CaptureStats {
+	stats := CaptureStats{
+		Packets: s.packets.Load(),
+		Bytes:   s.bytes.Load(),
+		Dropped: s.dropped.Load(),
+		Start:   s.start,
+	}
+	if ns := s.lastWrite.Load(); ns != 0 {
+		stats.LastWrite = time.Unix(0, ns)
+	}
+	return stats
+}
+
+// Pcapng returns the PcapngWriter created by App.NewPacketWriter, e.g. to add interfaces or write decryption secrets.
+// It is nil if the capture is not written in the pcapng format. Packets written with it directly are not counted
+// in the statistics nor filtered, use Writer for them.
+func (s *CaptureSession) Pcapng() *PcapngWriter {
+	pw, _ := s.writer.(*PcapngWriter)
+	return pw
+}
@@ -0,0 +1,52 @@
+package extcap
+
+import (
+	"fmt"
+	"time"
+)
+
+// StatsReporter periodically sends capture statistics to the interface toolbar
+type StatsReporter struct {
+	// Interval between reports, 10 seconds if not set
+	Interval time.Duration
+
+	// Logger is the button with ButtonRoleLogger whose log receives the reports. If nil, the status bar is used.
+	Logger *ControlButton
+}
+
+const defaultStatsInterval = 10 * time.Second
+
+// run sends reports until the capture context is done
+func (r *StatsReporter) run(session *CaptureSession) {
+	interval := r.Interval
+	if interval <= 0 {
+		interval = defaultStatsInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var prev CaptureStats
+	for {
+		select {
+		case <-session.Context.Done():
+			return
+		case <-ticker.C:
+		}
+
+		cur := session.Stats()
+		rate := float64(cur.Packets-prev.Packets) / interval.Seconds()
+		prev = cur
+
+		msg := fmt.Sprintf("%.1f packets/s, %d packets, %d bytes, %d dropped", rate, cur.Packets, cur.Bytes, cur.Dropped)
+		var err error
+		if r.Logger != nil {
+			err = session.Control.Logger(uint8(r.Logger.getNumber())).Printf("%s", msg)
+		} else {
+			err = session.Control.StatusbarMessage(msg)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
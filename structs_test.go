@@ -2,9 +2,13 @@ package extcap
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/urfave/cli/v2"
 )
 
 func TestStringerInterface(t *testing.T) {
@@ -28,6 +32,41 @@ func TestStringerInterface(t *testing.T) {
 			"arg {number=0}{call=--delay}{display=Time delay}{type=integer}{tooltip=Time delay between packages}{required=true}{range=1,15}",
 		},
 
+		{"Config Unsigned option",
+			NewConfigUnsignedOpt("port", "Remote port").Range(1, 65535).Default(22).Tooltip("SSH port"),
+			"arg {number=0}{call=--port}{display=Remote port}{type=unsigned}{tooltip=SSH port}{range=1,65535}{default=22}",
+		},
+
+		{"Config Long option",
+			NewConfigLongOpt("count", "Packet count").Default(10000000000),
+			"arg {number=0}{call=--count}{display=Packet count}{type=long}{default=10000000000}",
+		},
+
+		{"Config Double option",
+			NewConfigDoubleOpt("rate", "Sampling rate").Range(0.5, 10).Default(1.5).Tooltip("Samples per second"),
+			"arg {number=0}{call=--rate}{display=Sampling rate}{type=double}{tooltip=Samples per second}{range=0.5,10}{default=1.5}",
+		},
+
+		{"Interface with unsafe display",
+			CaptureInterface{"example1", "Example {1}\nfor extcap"},
+			"interface {value=example1}{display=Example (1) for extcap}",
+		},
+
+		{"Config String option with unsafe default",
+			NewConfigStringOpt("user", "User").Default("admin}{required=true"),
+			"arg {number=0}{call=--user}{display=User}{type=string}{default=admin)(required=true}",
+		},
+
+		{"Config Integer option with group",
+			NewConfigIntegerOpt("delay", "Time delay").Group("Timing {advanced}"),
+			"arg {number=0}{call=--delay}{display=Time delay}{type=integer}{group=Timing (advanced)}",
+		},
+
+		{"Config Integer option with min only",
+			NewConfigIntegerOpt("snaplen", "Snapshot length").Min(1),
+			"arg {number=0}{call=--snaplen}{display=Snapshot length}{type=integer}{range=1,2147483647}",
+		},
+
 		{"Config String option",
 			NewConfigStringOpt("server", "IP address for log server").Validation("\\b(?:(?:25[0-5]|2[0-4][0-9]|[01]?[0-9][0-9]?)\\.){3}(?:25[0-5]|2[0-4][0-9]|[01]?[0-9][0-9]?)\\b"),
 			"arg {number=0}{call=--server}{display=IP address for log server}{type=string}{validation=\\b(?:(?:25[0-5]|2[0-4][0-9]|[01]?[0-9][0-9]?)\\.){3}(?:25[0-5]|2[0-4][0-9]|[01]?[0-9][0-9]?)\\b}",
@@ -38,15 +77,88 @@ func TestStringerInterface(t *testing.T) {
 			"arg {number=0}{call=--message}{display=Message}{type=string}{tooltip=Package message content}{placeholder=Please enter a message here ...}",
 		},
 
+		{"Config Duration option",
+			NewConfigDurationOpt("duration", "Capture duration").Default(30 * time.Second),
+			"arg {number=0}{call=--duration}{display=Capture duration}{type=string}{validation=0|([0-9]+(\\.[0-9]+)?(ns|us|µs|ms|s|m|h))+}{default=30s}",
+		},
+
+		{"Config Multicheck option",
+			NewConfigMulticheckOpt("channels", "Channels").Values(Flatten(
+				Node("dev1", "Device 1", Node("ch1", "Channel 1"), Node("ch2", "Channel 2")),
+				Node("dev2", "Device 2"),
+			)...),
+			"arg {number=0}{call=--channels}{display=Channels}{type=multicheck}\n" +
+				"value {arg=0}{value=dev1}{display=Device 1}\n" +
+				"value {arg=0}{value=ch1}{display=Channel 1}{parent=dev1}\n" +
+				"value {arg=0}{value=ch2}{display=Channel 2}{parent=dev1}\n" +
+				"value {arg=0}{value=dev2}{display=Device 2}",
+		},
+
+		{"Config String option built fluently",
+			NewStringOpt("host", "Remote host").MustSet().Tooltip("Hostname or IP address").Group("Connection").Default("10.0.0.1"),
+			"arg {number=0}{call=--host}{display=Remote host}{type=string}{tooltip=Hostname or IP address}{required=true}{group=Connection}{default=10.0.0.1}",
+		},
+
+		{"Config Bool option required",
+			NewBoolOpt("verify", "Verify").MustSet(),
+			"arg {number=0}{call=--verify}{display=Verify}{type=boolflag}{required=true}",
+		},
+
 		{"Config Bool option",
 			NewConfigBoolOpt("verify", "Verify").Tooltip("Verify package content"),
 			"arg {number=0}{call=--verify}{display=Verify}{type=boolflag}{tooltip=Verify package content}",
 		},
 
-		// arg {number=3}{call=--remote}{display=Remote Channel}{tooltip=Remote Channel Selector}{type=selector}
-		//
-		// value {arg=3}{value=if1}{display=Remote1}{default=true}
-		// value {arg=3}{value=if2}{display=Remote2}{default=false}
+		{"Config Password option",
+			NewConfigPasswordOpt("password", "Password").Required(true).Tooltip("Remote password").DontSave(),
+			"arg {number=0}{call=--password}{display=Password}{type=password}{tooltip=Remote password}{required=true}{save=false}",
+		},
+
+		{"Config Timestamp option",
+			NewConfigTimestampOpt("ts", "Start Time").Default(time.Unix(1700000000, 0)).Tooltip("Capture start time"),
+			"arg {number=0}{call=--ts}{display=Start Time}{type=timestamp}{tooltip=Capture start time}{default=1700000000}",
+		},
+
+		{"Config EditSelector option",
+			NewConfigEditSelectorOpt("host", "Remote host").Values(OptValue{Value: "10.0.0.1", Display: "Router"}, OptValue{Value: "10.0.0.2", Display: "Switch"}).Default("10.0.0.1").Placeholder("hostname or IP"),
+			"arg {number=0}{call=--host}{display=Remote host}{type=editselector}{placeholder=hostname or IP}{default=10.0.0.1}\n" +
+				"value {arg=0}{value=10.0.0.1}{display=Router}\n" +
+				"value {arg=0}{value=10.0.0.2}{display=Switch}",
+		},
+
+		{"Config Selector option",
+			NewConfigSelectorOpt("remote", "Remote Channel").Tooltip("Remote Channel Selector").Values(OptValue{Value: "if1", Display: "Remote1", Default: true}, OptValue{Value: "if2", Display: "Remote2"}).Reload().Placeholder("Load interfaces..."),
+			"arg {number=0}{call=--remote}{display=Remote Channel}{type=selector}{tooltip=Remote Channel Selector}{placeholder=Load interfaces...}{reload=true}\n" +
+				"value {arg=0}{value=if1}{display=Remote1}{default=true}\n" +
+				"value {arg=0}{value=if2}{display=Remote2}",
+		},
+
+		{"Control button",
+			NewControlButton("Reconnect").Number(3).Tooltip("Reconnect to the remote host"),
+			"control {number=3}{type=button}{display=Reconnect}{tooltip=Reconnect to the remote host}",
+		},
+
+		{"Control logger button",
+			NewControlButton("Log").Role(ButtonRoleLogger),
+			"control {number=0}{type=button}{display=Log}{role=logger}",
+		},
+
+		{"Control selector",
+			NewControlSelector("Channel").Number(1).Values(OptValue{Value: "1", Display: "Channel 1", Default: true}, OptValue{Value: "2", Display: "Channel 2"}),
+			"control {number=1}{type=selector}{display=Channel}\n" +
+				"value {control=1}{value=1}{display=Channel 1}{default=true}\n" +
+				"value {control=1}{value=2}{display=Channel 2}",
+		},
+
+		{"Control boolean",
+			NewControlBoolean("Verbose").Number(2).Default(true).Tooltip("Include payloads"),
+			"control {number=2}{type=boolean}{display=Verbose}{tooltip=Include payloads}{default=true}",
+		},
+
+		{"Control string",
+			NewControlString("Note").Number(3).Placeholder("Enter note").Validation(`\w+`).Default("start"),
+			"control {number=3}{type=string}{display=Note}{placeholder=Enter note}{validation=\\w+}{default=start}",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -56,3 +168,273 @@ func TestStringerInterface(t *testing.T) {
 		})
 	}
 }
+
+func TestRangeValidation(t *testing.T) {
+	testCases := []struct {
+		name  string
+		opt   ConfigOption
+		value interface{}
+		valid bool
+	}{
+		{"Integer in range", NewConfigIntegerOpt("delay", "Delay").Range(1, 15), 15, true},
+		{"Integer below min", NewConfigIntegerOpt("delay", "Delay").Range(1, 15), 0, false},
+		{"Integer above max", NewConfigIntegerOpt("delay", "Delay").Max(15), 16, false},
+		{"Integer above int32", NewConfigIntegerOpt("delay", "Delay"), 1 << 40, false},
+		{"Unsigned above uint32", NewConfigUnsignedOpt("port", "Port"), uint(1 << 40), false},
+		{"Long without range", NewConfigLongOpt("count", "Count"), int64(1 << 40), true},
+		{"Double below min", NewConfigDoubleOpt("rate", "Rate").Min(0.5), 0.25, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.opt.validate(tc.value)
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrValueOutOfRange)
+			}
+		})
+	}
+}
+
+func TestStringValidation(t *testing.T) {
+	opt := NewConfigStringOpt("port", "Port").Validation("[0-9]+")
+
+	assert.NoError(t, opt.validate("8080"))
+	assert.NoError(t, opt.validate(""))
+	assert.ErrorIs(t, opt.validate("80a"), ErrValueInvalid)
+	assert.ErrorIs(t, opt.validate("a80"), ErrValueInvalid)
+}
+
+func TestOptionDependencies(t *testing.T) {
+	registered := map[string]ConfigOption{
+		"remote-username": NewConfigStringOpt("remote-username", "Username"),
+		"remote-password": NewConfigPasswordOpt("remote-password", "Password").Requires("remote-username"),
+		"ssh-key":         NewConfigStringOpt("ssh-key", "SSH key").ConflictsWith("remote-password"),
+	}
+
+	assert.NoError(t, checkDependencies(Options{"remote-username": "user", "remote-password": "secret"}, registered))
+	assert.NoError(t, checkDependencies(Options{"ssh-key": "id_rsa"}, registered))
+
+	err := checkDependencies(Options{"remote-password": "secret", "ssh-key": "id_rsa"}, registered)
+	assert.ErrorIs(t, err, ErrOptionDependency)
+	assert.Equal(t, "option dependency violated: --remote-password requires --remote-username\n"+
+		"option dependency violated: --ssh-key conflicts with --remote-password", err.Error())
+}
+
+func TestDefaultFunc(t *testing.T) {
+	opt := NewConfigStringOpt("iface", "Interface").DefaultFunc(func() (string, error) {
+		return "eth0", nil
+	})
+	assert.NoError(t, opt.resolveDefault())
+	assert.Equal(t, "arg {number=0}{call=--iface}{display=Interface}{type=string}{default=eth0}", opt.String())
+
+	failing := NewConfigIntegerOpt("delay", "Delay").DefaultFunc(func() (int, error) {
+		return 0, assert.AnError
+	})
+	assert.ErrorIs(t, failing.resolveDefault(), assert.AnError)
+}
+
+func TestValidateCallback(t *testing.T) {
+	opt := NewConfigIntegerOpt("delay", "Delay").Range(1, 15).Validate(func(val int) error {
+		if val%2 != 0 {
+			return fmt.Errorf("%d is odd", val)
+		}
+		return nil
+	})
+
+	assert.NoError(t, opt.validate(2))
+	assert.ErrorIs(t, opt.validate(16), ErrValueOutOfRange)
+
+	err := opt.validate(3)
+	assert.ErrorIs(t, err, ErrValueInvalid)
+	assert.Equal(t, "option --delay: invalid value: 3 is odd", err.Error())
+}
+
+func TestOptionNumbers(t *testing.T) {
+	opts := []ConfigOption{
+		NewConfigStringOpt("host", "Host").Number(10),
+		NewConfigIntegerOpt("port", "Port"),
+	}
+	assert.NoError(t, VerifyOptionNumbers(opts, map[string]int{"host": 10, "port": 1}))
+	assert.ErrorIs(t, VerifyOptionNumbers(opts, map[string]int{"host": 10, "port": 0}), ErrOptionRenumbered)
+
+	collision := []ConfigOption{
+		NewConfigStringOpt("host", "Host"),
+		NewConfigIntegerOpt("port", "Port").Number(0),
+	}
+	assert.ErrorIs(t, numberOptions(collision), ErrOptionNumberCollision)
+}
+
+func TestDurationConvert(t *testing.T) {
+	opt := NewConfigDurationOpt("duration", "Capture duration")
+
+	val, err := opt.convert("1m30s")
+	assert.NoError(t, err)
+	assert.Equal(t, 90*time.Second, Options{"duration": val}.Duration("duration"))
+
+	_, err = opt.convert("soon")
+	assert.ErrorIs(t, err, ErrValueInvalid)
+}
+
+func TestEnvVarDefault(t *testing.T) {
+	t.Setenv("EXTCAP_TEST_PORT", "2222")
+
+	opt := NewConfigUnsignedOpt("port", "Port").Default(22).EnvVar("EXTCAP_TEST_PORT")
+	assert.NoError(t, opt.resolveDefault())
+	assert.Equal(t, "arg {number=0}{call=--port}{display=Port}{type=unsigned}{default=2222}", opt.String())
+
+	t.Setenv("EXTCAP_TEST_PORT", "ssh")
+	assert.Error(t, opt.resolveDefault())
+}
+
+func TestLoadProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "profiles.json")
+	data := `{"office": {"remote-host": "10.0.0.1", "remote-port": 22, "verify": true}}`
+	assert.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+
+	values, err := loadProfile(path, "office")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"remote-host": "10.0.0.1", "remote-port": "22", "verify": "true"}, values)
+
+	_, err = loadProfile(path, "lab")
+	assert.ErrorIs(t, err, ErrProfileNotFound)
+}
+
+func TestRequiredGroups(t *testing.T) {
+	groups := []RequiredGroup{{Name: "authentication", Options: []string{"remote-password", "ssh-key"}}}
+
+	assert.NoError(t, checkRequiredGroups(Options{"ssh-key": "id_rsa"}, groups))
+
+	err := checkRequiredGroups(Options{"remote-username": "user"}, groups)
+	assert.ErrorIs(t, err, ErrRequiredGroup)
+	assert.Equal(t, "required option group not set: authentication: one of --remote-password, --ssh-key must be set", err.Error())
+}
+
+func TestOptionsJSON(t *testing.T) {
+	opts := []ConfigOption{
+		NewIntegerOpt("delay", "Time delay").Range(1, 15).Default(5).Tooltip("Time delay between packages").Number(3),
+		NewStringOpt("server", "Server").Validation("[a-z]+").MustSet().Group("Connection"),
+		NewPasswordOpt("password", "Password").Requires("user").EnvVar("EXTCAP_PASSWORD"),
+		NewDurationOpt("duration", "Duration").Default(time.Minute),
+		NewTimestampOpt("ts", "Start time").Default(time.Unix(1700000000, 0)),
+		NewSelectorOpt("remote", "Remote").Values(OptValue{Value: "if1", Display: "Remote1", Default: true}).Reload(),
+	}
+
+	data, err := MarshalOptions(opts)
+	assert.NoError(t, err)
+
+	decoded, err := UnmarshalOptions(data)
+	assert.NoError(t, err)
+	assert.Len(t, decoded, len(opts))
+	for i := range opts {
+		assert.Equal(t, fmt.Sprint(opts[i]), fmt.Sprint(decoded[i]))
+		assert.Equal(t, opts[i].env(), decoded[i].env())
+	}
+
+	again, err := MarshalOptions(decoded)
+	assert.NoError(t, err)
+	assert.JSONEq(t, string(data), string(again))
+
+	_, err = UnmarshalOptions([]byte(`[{"type": "radio", "call": "x", "display": "X"}]`))
+	assert.Error(t, err)
+}
+
+func TestTranslate(t *testing.T) {
+	translations := map[string]string{"Remote Channel": "Entfernter Kanal", "Remote1": "Entfernt 1"}
+	opt := NewSelectorOpt("remote", "Remote Channel").Values(OptValue{Value: "if1", Display: "Remote1"})
+	opt.setTranslator(func(key string) string {
+		if str, ok := translations[key]; ok {
+			return str
+		}
+		return key
+	})
+
+	assert.Equal(t, "arg {number=0}{call=--remote}{display=Entfernter Kanal}{type=selector}\n"+
+		"value {arg=0}{value=if1}{display=Entfernt 1}", opt.String())
+}
+
+func TestOptionError(t *testing.T) {
+	val, err := NewUnsignedOpt("port", "Port").convert("22")
+	assert.NoError(t, err)
+	assert.Equal(t, uint(22), val)
+
+	_, err = NewUnsignedOpt("port", "Port").convert("ssh")
+	var optErr *OptionError
+	assert.ErrorAs(t, err, &optErr)
+	assert.Equal(t, "port", optErr.Option)
+	assert.Equal(t, "ssh", optErr.Value)
+	assert.ErrorIs(t, err, ErrValueInvalid)
+	assert.Equal(t, `option --port: invalid value "ssh", expected unsigned integer`, err.Error())
+
+	val, err = NewTimestampOpt("ts", "Start time").convert("1700000000")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Unix(1700000000, 0), val)
+}
+
+func TestInterfaceArg(t *testing.T) {
+	testCases := []struct {
+		name      string
+		arguments []string
+		iface     string
+		found     bool
+	}{
+		{"Separate value", []string{"app", "--extcap-interface", "eth0", "--extcap-config"}, "eth0", true},
+		{"Inline value", []string{"app", "--extcap-config", "--extcap-interface=eth1"}, "eth1", true},
+		{"Missing value", []string{"app", "--extcap-interface"}, "", false},
+		{"No interface", []string{"app", "--extcap-interfaces"}, "", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			iface, found := interfaceArg(tc.arguments)
+			assert.Equal(t, tc.iface, iface)
+			assert.Equal(t, tc.found, found)
+		})
+	}
+}
+
+func TestCheckRequired(t *testing.T) {
+	registered := map[string]ConfigOption{
+		"host": NewStringOpt("host", "Host").MustSet(),
+		"port": NewUnsignedOpt("port", "Port"),
+	}
+
+	assert.NoError(t, checkRequired(Options{"host": "10.0.0.1"}, registered))
+	assert.ErrorIs(t, checkRequired(Options{"port": uint(22)}, registered), ErrOptionRequired)
+}
+
+// fileSelectOpt is an example of an option type implemented outside of the library
+type fileSelectOpt struct{}
+
+func (fileSelectOpt) Sentence(number int) string {
+	return fmt.Sprintf("arg {number=%d}{call=--logfile}{display=Log file}{type=fileselect}{mustexist=true}", number)
+}
+
+func (fileSelectOpt) Flag() cli.Flag {
+	return &cli.StringFlag{Name: "logfile"}
+}
+
+func (fileSelectOpt) Convert(val interface{}) (interface{}, error) {
+	return filepath.Clean(val.(string)), nil
+}
+
+func TestCustomOption(t *testing.T) {
+	opt := NewConfigCustomOpt(fileSelectOpt{}).Number(5).MustSet()
+	assert.Equal(t, "logfile", opt.call())
+	assert.True(t, opt.isRequired())
+	assert.Equal(t, "arg {number=5}{call=--logfile}{display=Log file}{type=fileselect}{mustexist=true}", fmt.Sprint(opt))
+
+	val, err := opt.convert("/tmp/../var/log/x.log")
+	assert.NoError(t, err)
+	assert.Equal(t, "/var/log/x.log", val)
+}
+
+func TestNumberControls(t *testing.T) {
+	controls := []ToolbarControl{NewControlButton("Start"), NewControlButton("Stop").Number(5), NewControlButton("Pause")}
+	assert.NoError(t, numberControls(controls))
+	assert.Equal(t, []int{0, 5, 2}, []int{controls[0].getNumber(), controls[1].getNumber(), controls[2].getNumber()})
+
+	controls = append(controls, NewControlButton("Resume").Number(2))
+	assert.ErrorIs(t, numberControls(controls), ErrControlNumberCollision)
+}
@@ -12,7 +12,7 @@ type VersionInfo struct {
 // Format to string in format
 // extcap {version=0.1.0}{help=<some help or URL}
 func (ver VersionInfo) String() string {
-	return fmt.Sprintf("extcap {version=%s}{help=%s}", ver.Info, ver.Help)
+	return fmt.Sprintf("extcap {version=%s}{help=%s}", escapeValue(ver.Info), escapeValue(ver.Help))
 }
 
 // CaptureInterface represents single network interface for capture
@@ -24,7 +24,7 @@ type CaptureInterface struct {
 // Format to string in format
 // interface {value=example1}{display=Example interface 1 for extcap}
 func (iface CaptureInterface) String() string {
-	return fmt.Sprintf("interface {value=%s}{display=%s}", iface.Value, iface.Display)
+	return fmt.Sprintf("interface {value=%s}{display=%s}", escapeValue(iface.Value), escapeText(iface.Display))
 }
 
 // DLT represents link type supported by interface
@@ -37,5 +37,5 @@ type DLT struct {
 // Format to string in format
 // dlt {number=147}{name=USER1}{display=Demo Implementation for Extcap}
 func (dlt DLT) String() string {
-	return fmt.Sprintf("dlt {number=%d}{name=%s}{display=%s}", dlt.Number, dlt.Name, dlt.Display)
+	return fmt.Sprintf("dlt {number=%d}{name=%s}{display=%s}", dlt.Number, escapeValue(dlt.Name), escapeText(dlt.Display))
 }
@@ -0,0 +1,82 @@
+package extcap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// RestartPolicy configures Supervise
+type RestartPolicy struct {
+	// Backoff sets the delays between the restarts, Backoff.MaxAttempts limits consecutive runs
+	// and Backoff.OnRetry is called before each restart
+	Backoff Backoff
+	// RestartOnExit restarts the capture function also when it returns nil, e.g. when the remote source
+	// closed the connection cleanly
+	RestartOnExit bool
+	// ResetAfter resets the run count and the backoff when the capture function ran at least this long,
+	// 0 never resets
+	ResetAfter time.Duration
+}
+
+// Supervise runs the capture function with w and restarts it when it fails according to the policy,
+// so Wireshark sees one continuous capture across source restarts. The function can not close w.
+//
+// It is not restarted when ctx is done or w returned ErrPipeClosed or ErrAutostop, i.e. the capture was stopped,
+// Supervise returns nil then. Permanent errors are returned immediately.
+func Supervise(ctx context.Context, w PacketWriter, policy RestartPolicy, capture func(ctx context.Context, w PacketWriter) error) error {
+	w = unclosableWriter{w}
+	runs := 0
+	for {
+		started := time.Now()
+		err := capture(ctx, w)
+		if ctx.Err() != nil || errors.Is(err, ErrPipeClosed) || errors.Is(err, ErrAutostop) {
+			return nil
+		}
+		if err == nil && !policy.RestartOnExit {
+			return nil
+		}
+		var permanent permanentError
+		if errors.As(err, &permanent) {
+			return permanent.err
+		}
+
+		if policy.ResetAfter > 0 && time.Since(started) >= policy.ResetAfter {
+			runs = 0
+		}
+		runs++
+		if policy.Backoff.MaxAttempts > 0 && runs >= policy.Backoff.MaxAttempts {
+			if err == nil {
+				// the last run exited cleanly, there is no error to wrap
+				return fmt.Errorf("%w after %d attempts", ErrRetriesExhausted, runs)
+			}
+			return fmt.Errorf("%w after %d attempts: %w", ErrRetriesExhausted, runs, err)
+		}
+
+		delay := policy.Backoff.jittered(runs)
+		if policy.Backoff.OnRetry != nil {
+			policy.Backoff.OnRetry(runs, err, delay)
+		}
+		if !sleepContext(ctx, delay) {
+			return nil
+		}
+	}
+}
+
+// unclosableWriter hides Close of the writer, it forwards the batches and the drops
+type unclosableWriter struct {
+	w PacketWriter
+}
+
+func (u unclosableWriter) WritePacket(ts time.Time, data []byte, origLen int) error {
+	return u.w.WritePacket(ts, data, origLen)
+}
+
+func (u unclosableWriter) WritePackets(packets []Packet) error {
+	return WritePackets(u.w, packets)
+}
+
+func (u unclosableWriter) ReportDropped(id int, n uint64) error {
+	return reportDropped(u.w, id, n)
+}
@@ -0,0 +1,68 @@
+package extcap
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSupervise(t *testing.T) {
+	errDisconnected := errors.New("disconnected")
+	w := new(recordingPacketWriter)
+	policy := RestartPolicy{Backoff: Backoff{Initial: time.Millisecond, MaxAttempts: 3}}
+
+	runs := 0
+	err := Supervise(context.Background(), w, policy, func(ctx context.Context, w PacketWriter) error {
+		runs++
+		_, closer := w.(io.Closer)
+		assert.False(t, closer)
+		assert.NoError(t, w.WritePacket(time.Now(), []byte{byte(runs)}, 1))
+		if runs < 3 {
+			return errDisconnected
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{{1}, {2}, {3}}, w.packets)
+
+	runs = 0
+	err = Supervise(context.Background(), w, policy, func(context.Context, PacketWriter) error {
+		runs++
+		return errDisconnected
+	})
+	assert.ErrorIs(t, err, ErrRetriesExhausted)
+	assert.Equal(t, 3, runs)
+
+	err = Supervise(context.Background(), w, policy, func(context.Context, PacketWriter) error {
+		return ErrPipeClosed
+	})
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runs = 0
+	policy = RestartPolicy{Backoff: Backoff{Initial: time.Millisecond}, RestartOnExit: true}
+	err = Supervise(ctx, w, policy, func(context.Context, PacketWriter) error {
+		if runs++; runs == 5 {
+			cancel()
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 5, runs)
+}
+
+func TestSuperviseRestartOnExitExhausted(t *testing.T) {
+	policy := RestartPolicy{Backoff: Backoff{Initial: time.Millisecond, MaxAttempts: 2}, RestartOnExit: true}
+	runs := 0
+	err := Supervise(context.Background(), new(recordingPacketWriter), policy, func(context.Context, PacketWriter) error {
+		runs++
+		return nil
+	})
+	assert.ErrorIs(t, err, ErrRetriesExhausted)
+	assert.EqualError(t, err, ErrRetriesExhausted.Error()+" after 2 attempts")
+	assert.Equal(t, 2, runs)
+}
@@ -0,0 +1,81 @@
+package extcap
+
+import (
+	"sync"
+	"time"
+)
+
+// TimestampMode selects where the writers take packet timestamps from
+type TimestampMode int
+
+const (
+	// TimestampSourceProvided keeps timestamps passed by the source, it is the default
+	TimestampSourceProvided TimestampMode = iota
+	// TimestampWallClock stamps packets with the wall clock at write time
+	TimestampWallClock
+	// TimestampMonotonic stamps packets with the writer creation time advanced by the monotonic clock,
+	// so the timeline is not affected by wall clock adjustments during the capture
+	TimestampMonotonic
+)
+
+// Timestamps sets the timestamp mode of the writer
+func Timestamps(mode TimestampMode) WriterOption {
+	return func(cfg *writerConfig) {
+		cfg.tsMode = mode
+	}
+}
+
+// timestamp returns timestamp of the packet written now according to the timestamp mode
+func (cfg *writerConfig) timestamp(ts time.Time) time.Time {
+	switch cfg.tsMode {
+	case TimestampWallClock:
+		return time.Now()
+	case TimestampMonotonic:
+		// time.Since uses the monotonic reading of base
+		return cfg.base.Round(0).Add(time.Since(cfg.base))
+	default:
+		if cfg.corrector != nil {
+			return cfg.corrector.Correct(ts)
+		}
+		return ts
+	}
+}
+
+// SourceClock converts timestamps of a remote source, expressed as time since an epoch of the source
+// (e.g. device uptime), to local time. It is synchronized by pairing a source time with the local time it was observed at.
+type SourceClock struct {
+	mu     sync.Mutex
+	source time.Duration
+	local  time.Time
+}
+
+// NewSourceClock creates SourceClock synchronized with the source time observed now
+func NewSourceClock(source time.Duration) *SourceClock {
+	c := &SourceClock{}
+	c.Sync(source, time.Now())
+	return c
+}
+
+// Sync pairs the source time with the local time it was observed at
+func (c *SourceClock) Sync(source time.Duration, local time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.source, c.local = source, local
+}
+
+// Time converts the source time to local time
+func (c *SourceClock) Time(source time.Duration) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.local.Add(source - c.source)
+}
+
+// Source converts local time to the source time
+func (c *SourceClock) Source(local time.Time) time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.source + local.Sub(c.local)
+}
@@ -0,0 +1,32 @@
+package extcap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimestamps(t *testing.T) {
+	for _, mode := range []TimestampMode{TimestampWallClock, TimestampMonotonic} {
+		buf := new(bytes.Buffer)
+		pw, err := NewPcapWriter(buf, DLT{Number: 1}, Timestamps(mode))
+		assert.NoError(t, err)
+
+		buf.Reset()
+		before := time.Now().Unix()
+		assert.NoError(t, pw.WritePacket(time.Time{}, []byte{1}, 1))
+		assert.InDelta(t, before, binary.LittleEndian.Uint32(buf.Bytes()), 1)
+	}
+}
+
+func TestSourceClock(t *testing.T) {
+	local := time.Unix(1700000000, 0)
+	c := NewSourceClock(0)
+	c.Sync(time.Hour, local)
+
+	assert.Equal(t, local.Add(time.Second), c.Time(time.Hour+time.Second))
+	assert.Equal(t, time.Hour-time.Minute, c.Source(local.Add(-time.Minute)))
+}
@@ -0,0 +1,66 @@
+package extcap
+
+import "time"
+
+// Transform changes the packet before it is written, e.g. to redact or rewrite headers.
+// It returns the new timestamp and data, or false if the packet should be dropped.
+// The data may be modified in place.
+type Transform func(ts time.Time, data []byte) (time.Time, []byte, bool)
+
+// TransformWriter applies the transforms in order to each packet before writing it to the underlying writer
+type TransformWriter struct {
+	w          PacketWriter
+	transforms []Transform
+}
+
+// NewTransformWriter creates TransformWriter writing to w
+func NewTransformWriter(w PacketWriter, transforms ...Transform) *TransformWriter {
+	return &TransformWriter{w: w, transforms: transforms}
+}
+
+// WritePacket transforms the packet and writes it unless a transform dropped it.
+// The original length is kept if the transforms shortened the data.
+func (tw *TransformWriter) WritePacket(ts time.Time, data []byte, origLen int) error {
+	for _, t := range tw.transforms {
+		var keep bool
+		if ts, data, keep = t(ts, data); !keep {
+			return nil
+		}
+	}
+	return tw.w.WritePacket(ts, data, max(origLen, len(data)))
+}
+
+// WritePackets transforms the packets of the batch and writes those not dropped as one batch
+func (tw *TransformWriter) WritePackets(packets []Packet) error {
+	kept := make([]Packet, 0, len(packets))
+	for _, p := range packets {
+		ts, data, keep := p.Timestamp, p.Data, true
+		for _, t := range tw.transforms {
+			if ts, data, keep = t(ts, data); !keep {
+				break
+			}
+		}
+		if keep {
+			kept = append(kept, Packet{Timestamp: ts, Data: data, OrigLen: max(p.OrigLen, len(data))})
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	return WritePackets(tw.w, kept)
+}
+
+// Flush flushes the transformed writer
+func (tw *TransformWriter) Flush() error {
+	return flushWriter(tw.w)
+}
+
+// ReportDropped passes the drops to the transformed writer, packets dropped by the transforms are not counted
+func (tw *TransformWriter) ReportDropped(id int, n uint64) error {
+	return reportDropped(tw.w, id, n)
+}
+
+// Close closes the transformed writer
+func (tw *TransformWriter) Close() error {
+	return closeWriter(tw.w)
+}
@@ -0,0 +1,39 @@
+package extcap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransformWriter(t *testing.T) {
+	w := new(recordingPacketWriter)
+	truncate := func(ts time.Time, data []byte) (time.Time, []byte, bool) {
+		return ts, data[:1], true
+	}
+	dropEmpty := func(ts time.Time, data []byte) (time.Time, []byte, bool) {
+		return ts, data, data[0] != 0
+	}
+	tw := NewTransformWriter(w, dropEmpty, truncate)
+
+	assert.NoError(t, tw.WritePacket(time.Now(), []byte{1, 2, 3}, 3))
+	assert.NoError(t, tw.WritePacket(time.Now(), []byte{0, 2, 3}, 3))
+	assert.Equal(t, [][]byte{{1}}, w.packets)
+	assert.Equal(t, []int{3}, w.origLen)
+}
+
+func TestTransformWriterBatch(t *testing.T) {
+	w := new(batchRecordingWriter)
+	dropEmpty := func(ts time.Time, data []byte) (time.Time, []byte, bool) {
+		return ts, data[:1], data[0] != 0
+	}
+	tw := NewTransformWriter(w, dropEmpty)
+
+	assert.NoError(t, tw.WritePackets([]Packet{{Data: []byte{1, 2}, OrigLen: 2}, {Data: []byte{0, 2}, OrigLen: 2}, {Data: []byte{3}}}))
+	assert.NoError(t, tw.Flush())
+	assert.Equal(t, [][]byte{{1}, {3}}, w.packets)
+	assert.Equal(t, []int{2, 1}, w.origLen)
+	assert.Equal(t, 1, w.batches)
+	assert.Equal(t, 1, w.flushes)
+}
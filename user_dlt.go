@@ -0,0 +1,99 @@
+package extcap
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Link types reserved for private use, Wireshark calls them WTAP_ENCAP_USER0-15 and dissects them
+// with the protocols configured in the DLT_USER preferences
+const (
+	DLTUser0  = 147
+	DLTUser15 = 162
+)
+
+// UserRecordHeaderSize is the size of the TLV header of user records, it is the header size to configure
+// in the DLT_USER preferences of Wireshark, so the value is dissected with the payload protocol, e.g. json
+const UserRecordHeaderSize = 6
+
+// UserDLT returns DLT_USER<n> link type for n from 0 to 15
+func UserDLT(n int, display string) (DLT, error) {
+	if n < 0 || DLTUser0+n > DLTUser15 {
+		return DLT{}, fmt.Errorf("%w: user link type %d, expected 0-15", ErrUnsupportedLinkType, n)
+	}
+	return DLT{Number: DLTUser0 + n, Name: fmt.Sprintf("USER%d", n), Display: display}, nil
+}
+
+// UserTLV is a user record: 2 bytes type and 4 bytes length in network byte order, followed by the value
+type UserTLV struct {
+	Type  uint16
+	Value []byte
+}
+
+// AppendUserTLV appends the record to b
+func AppendUserTLV(b []byte, typ uint16, value []byte) []byte {
+	b = binary.BigEndian.AppendUint16(b, typ)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(value)))
+	return append(b, value...)
+}
+
+// ParseUserTLVs splits the packet data to records, the values refer to data
+func ParseUserTLVs(data []byte) ([]UserTLV, error) {
+	var tlvs []UserTLV
+	for len(data) > 0 {
+		if len(data) < UserRecordHeaderSize {
+			return nil, fmt.Errorf("%w: %d bytes left for header", ErrInvalidUserRecord, len(data))
+		}
+		typ, length := binary.BigEndian.Uint16(data), binary.BigEndian.Uint32(data[2:])
+		if uint64(length) > uint64(len(data)-UserRecordHeaderSize) {
+			return nil, fmt.Errorf("%w: value of %d bytes, %d left", ErrInvalidUserRecord, length, len(data)-UserRecordHeaderSize)
+		}
+		end := UserRecordHeaderSize + int(length)
+		tlvs = append(tlvs, UserTLV{Type: typ, Value: data[UserRecordHeaderSize:end]})
+		data = data[end:]
+	}
+	return tlvs, nil
+}
+
+// UserRecordWriter writes application records, e.g. logs, protobufs or JSON events, as packets of a DLT_USER
+// link type, so non-network sources can be captured. Every record is one packet.
+type UserRecordWriter struct {
+	w   PacketWriter
+	mu  sync.Mutex
+	buf []byte
+}
+
+// NewUserRecordWriter creates UserRecordWriter writing to w, which should be created for a link type from UserDLT
+func NewUserRecordWriter(w PacketWriter) *UserRecordWriter {
+	return &UserRecordWriter{w: w}
+}
+
+// WriteRecord writes the value with the TLV header as a packet
+func (uw *UserRecordWriter) WriteRecord(ts time.Time, typ uint16, value []byte) error {
+	uw.mu.Lock()
+	defer uw.mu.Unlock()
+	uw.buf = AppendUserTLV(uw.buf[:0], typ, value)
+	return uw.w.WritePacket(ts, uw.buf, len(uw.buf))
+}
+
+// WriteJSON writes v encoded as JSON with the TLV header as a packet
+func (uw *UserRecordWriter) WriteJSON(ts time.Time, typ uint16, v any) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("unable to encode record: %w", err)
+	}
+	return uw.WriteRecord(ts, typ, value)
+}
+
+// ReportDropped passes the drops of records to the underlying writer
+func (uw *UserRecordWriter) ReportDropped(id int, n uint64) error {
+	return reportDropped(uw.w, id, n)
+}
+
+// Close closes the underlying writer
+func (uw *UserRecordWriter) Close() error {
+	return closeWriter(uw.w)
+}
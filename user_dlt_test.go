@@ -0,0 +1,37 @@
+package extcap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserDLT(t *testing.T) {
+	dlt, err := UserDLT(1, "Demo")
+	require.NoError(t, err)
+	assert.Equal(t, "dlt {number=148}{name=USER1}{display=Demo}", dlt.String())
+
+	_, err = UserDLT(16, "Demo")
+	assert.ErrorIs(t, err, ErrUnsupportedLinkType)
+}
+
+func TestUserRecordWriter(t *testing.T) {
+	w := new(recordingPacketWriter)
+	uw := NewUserRecordWriter(w)
+	require.NoError(t, uw.WriteRecord(time.Now(), 1, []byte("log line")))
+	require.NoError(t, uw.WriteJSON(time.Now(), 2, map[string]int{"level": 3}))
+
+	assert.Equal(t, append([]byte{0, 1, 0, 0, 0, 8}, "log line"...), w.packets[0])
+	tlvs, err := ParseUserTLVs(w.packets[1])
+	require.NoError(t, err)
+	assert.Equal(t, []UserTLV{{Type: 2, Value: []byte(`{"level":3}`)}}, tlvs)
+
+	tlvs, err = ParseUserTLVs(AppendUserTLV(AppendUserTLV(nil, 1, nil), 2, []byte{9}))
+	require.NoError(t, err)
+	assert.Equal(t, []UserTLV{{Type: 1, Value: []byte{}}, {Type: 2, Value: []byte{9}}}, tlvs)
+
+	_, err = ParseUserTLVs([]byte{0, 1, 0, 0, 0, 5, 1})
+	assert.ErrorIs(t, err, ErrInvalidUserRecord)
+}
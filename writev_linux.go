@@ -0,0 +1,45 @@
+//go:build linux
+
+package extcap
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// iovMax is the maximum number of buffers of a single writev on Linux
+const iovMax = 1024
+
+// writev writes bufs to the file with writev, so packet data is not copied next to its header
+func writev(f *os.File, bufs [][]byte) error {
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var werr error
+	err = rc.Write(func(fd uintptr) bool {
+		for len(bufs) > 0 {
+			n, err := unix.Writev(int(fd), bufs[:min(len(bufs), iovMax)])
+			if errors.Is(err, unix.EINTR) {
+				continue
+			}
+			if errors.Is(err, unix.EAGAIN) {
+				// wait until the fifo is writable
+				return false
+			}
+			if err != nil {
+				werr = err
+				return true
+			}
+			bufs = consumeBuffers(bufs, n)
+		}
+		return true
+	})
+	if err != nil {
+		return err
+	}
+	return werr
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package extcap
+
+import (
+	"net"
+	"os"
+)
+
+// writev writes bufs to the file one by one, vectored writes are used on Linux only
+func writev(f *os.File, bufs [][]byte) error {
+	buffers := net.Buffers(bufs)
+	_, err := buffers.WriteTo(f)
+	return err
+}